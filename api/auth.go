@@ -0,0 +1,152 @@
+package api
+
+import (
+	"VentureBackend/utils"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiSessionTTL is how long a session created with an authenticator code
+// authorizes API requests.
+const apiSessionTTL = 15 * time.Minute
+
+// apiSessionHeader carries the session token on API requests.
+const apiSessionHeader = "X-Api-Session"
+
+type apiSession struct {
+	AccountID string
+	Admin     string
+}
+
+func AddAuthApiRoute(router *gin.Engine) {
+	router.POST("/api/venturebackend/session", CreateApiSession)
+}
+
+// apiTwoFactorAdmins lists the admins of API_2FA_ADMINS. When it is empty
+// the API key alone authorizes requests.
+func apiTwoFactorAdmins() []string {
+	admins := []string{}
+	for _, name := range strings.Split(os.Getenv("API_2FA_ADMINS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			admins = append(admins, name)
+		}
+	}
+	return admins
+}
+
+func isApiTwoFactorAdmin(admin string) bool {
+	for _, name := range apiTwoFactorAdmins() {
+		if admin != "" && strings.EqualFold(name, admin) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkApiKey(c *gin.Context) bool {
+	apikey := c.Query("apikey")
+	if apikey == "" || apikey != os.Getenv("bApiKey") {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing API key."})
+		return false
+	}
+	return true
+}
+
+// authorizeApiRequest checks the API key. When API_2FA_ADMINS lists admin
+// usernames, the request must also carry a session one of them created with
+// their authenticator through CreateApiSession.
+func authorizeApiRequest(c *gin.Context) bool {
+	if !checkApiKey(c) {
+		return false
+	}
+	if len(apiTwoFactorAdmins()) == 0 {
+		return true
+	}
+
+	token := c.GetHeader(apiSessionHeader)
+	raw, ok := utils.Store.Get("apiSession:" + token)
+	session, valid := raw.(apiSession)
+	if token == "" || !ok || !valid || !isApiTwoFactorAdmin(session.Admin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or expired API session, create one at /api/venturebackend/session."})
+		return false
+	}
+	return true
+}
+
+// CreateApiSession verifies an admin's authenticator code once and returns a
+// session token for the X-Api-Session header, so codes aren't sent with every
+// request. Recovery codes are only accepted by game logins.
+func CreateApiSession(c *gin.Context) {
+	if !checkApiKey(c) {
+		return
+	}
+	if len(apiTwoFactorAdmins()) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "API two-factor authentication is not enabled."})
+		return
+	}
+
+	admin := c.Query("admin")
+	if !isApiTwoFactorAdmin(admin) {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing admin."})
+		return
+	}
+
+	user, err := utils.FindUserByUsername(admin)
+	if err != nil || user == nil || !user.TwoFactor.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Admin account has no two-factor authentication set up."})
+		return
+	}
+
+	// Codes are throttled by the same counters as game logins, so the API
+	// can't be used to guess an admin's authenticator. Its replay key is its
+	// own, so a code used here doesn't block the admin's game login.
+	loginName := utils.LoginName(user)
+	if lockout := utils.LoginLockout(loginName, c.ClientIP()); lockout > 0 {
+		apiLockedError(c, lockout)
+		return
+	}
+	if !utils.VerifyTOTP(user.TwoFactor.Secret, c.Query("otp"), "api:"+user.AccountID) {
+		if lockout := utils.RecordLoginFailure(loginName, c.ClientIP()); lockout > 0 {
+			apiLockedError(c, lockout)
+			return
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing two-factor code."})
+		return
+	}
+	utils.RecordLoginSuccess(loginName)
+
+	token, err := newApiSessionToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to create API session."})
+		return
+	}
+	utils.Store.SetTTL("apiSession:"+token, apiSession{AccountID: user.AccountID, Admin: user.Username}, apiSessionTTL)
+	utils.Backend.Logf("API session created for %s", user.Username)
+
+	c.JSON(http.StatusOK, gin.H{
+		"session":   token,
+		"header":    apiSessionHeader,
+		"expiresIn": int(apiSessionTTL.Seconds()),
+	})
+}
+
+func newApiSessionToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func apiLockedError(c *gin.Context, lockout time.Duration) {
+	seconds := int(lockout.Round(time.Second).Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.JSON(http.StatusTooManyRequests, gin.H{"code": "429", "error": "Too many failed attempts, try again in " + strconv.Itoa(seconds) + " seconds."})
+}
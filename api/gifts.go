@@ -0,0 +1,186 @@
+package api
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func AddGiftsApiRoute(router *gin.Engine) {
+	router.GET("/api/venturebackend/gifts", GetGifts)
+	router.GET("/api/venturebackend/gifts/reverse", ReverseGift)
+}
+
+// GetGifts lists the gifts a user sent or received, newest first.
+func GetGifts(c *gin.Context) {
+	username := c.Query("username")
+
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Missing username."})
+		return
+	}
+
+	limit := int64(50)
+	if raw := c.Query("limit"); raw != "" {
+		v, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || v < 1 || v > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "limit must be between 1 and 500."})
+			return
+		}
+		limit = v
+	}
+
+	user, err := utils.FindUserByUsername(username)
+	if err != nil || user == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "User not found."})
+		return
+	}
+
+	gifts, err := utils.FindGifts(user.AccountID, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to read gifts."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"accountId": user.AccountID,
+		"gifts":     gifts,
+	})
+}
+
+// ReverseGift takes a gift's items and gift box back from the receiver and,
+// unless refund=false, gives the sender their currency back.
+func ReverseGift(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	id, err := primitive.ObjectIDFromHex(c.Query("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Missing or invalid gift id."})
+		return
+	}
+	refund := c.Query("refund") != "false"
+
+	reversedBy := c.Query("admin")
+	if reversedBy == "" {
+		reversedBy = "[Administrator]"
+	}
+
+	gift, err := utils.MarkGiftReversed(id, reversedBy)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Gift not found or already reversed."})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to reverse gift."})
+		return
+	}
+
+	removed := []string{}
+	_, ok := updateProfiles(c, gift.ReceiverID, func(doc *models.Profiles) ([]string, error) {
+		removed = removed[:0]
+		changed := map[string]bool{}
+		take := func(profileID, itemID string) {
+			profile, ok := doc.Profiles[profileID].(map[string]interface{})
+			if !ok {
+				return
+			}
+			items, ok := profile["items"].(map[string]interface{})
+			if !ok {
+				return
+			}
+			if _, exists := items[itemID]; exists {
+				delete(items, itemID)
+				removed = append(removed, itemID)
+				changed[profileID] = true
+			}
+		}
+		for _, item := range gift.GrantedItems {
+			take(item.ProfileID, item.ItemID)
+		}
+		take("common_core", gift.GiftBoxID)
+
+		ids := []string{}
+		for profileID := range changed {
+			ids = append(ids, profileID)
+		}
+		return ids, nil
+	})
+	if !ok {
+		utils.Error.Logf("Gift %s was marked reversed but its items could not be taken back", gift.ID.Hex())
+		return
+	}
+
+	refunded := 0
+	if refund && gift.Price > 0 && gift.ChargedItemID != "" {
+		_, ok = updateProfiles(c, gift.SenderID, func(doc *models.Profiles) ([]string, error) {
+			commonCore, ok := doc.Profiles["common_core"].(map[string]interface{})
+			if !ok {
+				return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Common core profile not found."})
+			}
+			items, _ := commonCore["items"].(map[string]interface{})
+			currency, ok := items[gift.ChargedItemID].(map[string]interface{})
+			if !ok {
+				return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Sender currency item missing."})
+			}
+			currency["quantity"] = toInt(currency["quantity"]) + gift.Price
+			refunded = gift.Price
+			return []string{"common_core"}, nil
+		})
+		if !ok {
+			utils.Error.Logf("Gift %s was reversed but the sender could not be refunded", gift.ID.Hex())
+			return
+		}
+	}
+
+	templates := map[string]string{}
+	for _, item := range gift.GrantedItems {
+		templates[item.ItemID] = item.TemplateID
+	}
+	takenBack := []models.ReceiptItem{}
+	for _, itemID := range removed {
+		if templateID, ok := templates[itemID]; ok {
+			takenBack = append(takenBack, models.ReceiptItem{TemplateID: templateID, ItemID: itemID, Quantity: 1})
+		}
+	}
+	note := "Gift " + gift.ID.Hex() + " reversed by " + reversedBy
+	utils.RecordReceipt(models.Receipt{
+		AccountID:        gift.ReceiverID,
+		Type:             models.ReceiptGiftReversal,
+		OfferID:          gift.OfferID,
+		Items:            takenBack,
+		RelatedAccountID: gift.SenderID,
+		Note:             note,
+	})
+	if refunded > 0 {
+		utils.RecordReceipt(models.Receipt{
+			AccountID:        gift.SenderID,
+			Type:             models.ReceiptGiftReversal,
+			OfferID:          gift.OfferID,
+			CurrencyType:     gift.CurrencyType,
+			Amount:           refunded,
+			RelatedAccountID: gift.ReceiverID,
+			Note:             note,
+		})
+	}
+
+	utils.Backend.Logf("Gift %s from %s to %s reversed by %s", gift.ID.Hex(), gift.SenderID, gift.ReceiverID, reversedBy)
+	c.JSON(http.StatusOK, gin.H{
+		"id":           gift.ID.Hex(),
+		"senderId":     gift.SenderID,
+		"receiverId":   gift.ReceiverID,
+		"removedItems": removed,
+		"refunded":     refunded,
+	})
+}
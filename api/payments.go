@@ -0,0 +1,89 @@
+package api
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The payment routes stand in for a real payment provider: testers settle
+// the orders RealMoney offers place through the callback.
+func AddPaymentsApiRoute(router *gin.Engine) {
+	router.GET("/api/venturebackend/payments", GetPayment)
+	router.POST("/api/venturebackend/payments/callback", PaymentCallback)
+}
+
+// GetPayment shows an order.
+func GetPayment(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	orderId := c.Query("orderId")
+	if orderId == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Missing orderId."})
+		return
+	}
+
+	payment, err := utils.FindPayment(orderId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to read order."})
+		return
+	}
+	if payment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Order not found."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment": payment})
+}
+
+type paymentCallbackRequest struct {
+	OrderID string               `json:"orderId"`
+	Status  models.PaymentStatus `json:"status"`
+}
+
+// PaymentCallback settles a pending order as completed or failed and
+// credits completed orders. Repeating a callback is safe, so a provider may
+// retry until it gets a 200.
+func PaymentCallback(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	var body paymentCallbackRequest
+	if err := c.ShouldBindJSON(&body); err != nil || body.OrderID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Invalid request body."})
+		return
+	}
+	if body.Status != models.PaymentCompleted && body.Status != models.PaymentFailed {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "status must be completed or failed."})
+		return
+	}
+
+	payment, err := utils.SettlePayment(body.OrderID, body.Status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to settle order."})
+		return
+	}
+	if payment == nil {
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Order not found."})
+		return
+	}
+	if payment.Status != body.Status {
+		c.JSON(http.StatusConflict, gin.H{"code": "409", "error": "Order was already " + string(payment.Status) + "."})
+		return
+	}
+
+	if payment.Status == models.PaymentCompleted {
+		if _, err := utils.FulfillPayment(payment); err != nil {
+			utils.Error.Logf("Failed to fulfill order %s: %v", payment.OrderID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to credit order."})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Order " + string(payment.Status) + ".", "payment": payment})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// profileError aborts a profile update with the response to send.
+type profileError struct {
+	status int
+	body   gin.H
+}
+
+func (e *profileError) Error() string {
+	msg, _ := e.body["error"].(string)
+	return msg
+}
+
+func abortProfile(status int, body gin.H) error {
+	return &profileError{status: status, body: body}
+}
+
+// updateProfiles runs a revision-checked profile update and answers the
+// request itself when it fails.
+func updateProfiles(c *gin.Context, accountID string, mutate func(doc *models.Profiles) ([]string, error)) (*models.Profiles, bool) {
+	doc, err := utils.UpdateProfiles(accountID, mutate)
+	if err == nil {
+		return doc, true
+	}
+
+	var pe *profileError
+	switch {
+	case errors.As(err, &pe):
+		c.JSON(pe.status, pe.body)
+	case errors.Is(err, utils.ErrProfileRevisionMismatch):
+		c.JSON(http.StatusConflict, gin.H{"code": "409", "error": "Profile was changed by another request, try again."})
+	case errors.Is(err, mongo.ErrNoDocuments):
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Profile not found."})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to update profile in DB."})
+	}
+	return nil, false
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"VentureBackend/utils"
+	"encoding/csv"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AddReceiptsApiRoute(router *gin.Engine) {
+	router.GET("/api/venturebackend/receipts", GetReceipts)
+}
+
+// GetReceipts exports a user's receipts, oldest first, with the running
+// V-Bucks total after each one. format=csv returns a spreadsheet instead.
+func GetReceipts(c *gin.Context) {
+	username := c.Query("username")
+
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	if username == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Missing username."})
+		return
+	}
+
+	user, err := utils.FindUserByUsername(username)
+	if err != nil || user == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "User not found."})
+		return
+	}
+
+	receipts, err := utils.FindReceipts(user.AccountID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to read receipts."})
+		return
+	}
+
+	currentBalance := 0
+	if doc, err := utils.FindProfileByAccountID(user.AccountID); err == nil {
+		if commonCore, ok := doc.Profiles["common_core"].(map[string]interface{}); ok {
+			items, _ := commonCore["items"].(map[string]interface{})
+			for _, raw := range items {
+				item, _ := raw.(map[string]interface{})
+				templateID, _ := item["templateId"].(string)
+				if strings.HasPrefix(strings.ToLower(templateID), "currency:mtx") {
+					currentBalance += toInt(item["quantity"])
+				}
+			}
+		}
+	}
+
+	spent, gained, running := 0, 0, 0
+	balances := make([]int, len(receipts))
+	for i, receipt := range receipts {
+		// Totals are in V-Bucks; GameItem amounts are counted in their own
+		// currency item.
+		if receipt.CurrencyType != "" && !strings.EqualFold(receipt.CurrencyType, "MtxCurrency") {
+			balances[i] = running
+			continue
+		}
+		if receipt.Amount < 0 {
+			spent -= receipt.Amount
+		} else {
+			gained += receipt.Amount
+		}
+		running += receipt.Amount
+		balances[i] = running
+	}
+
+	if c.Query("format") == "csv" {
+		c.Header("Content-Disposition", "attachment; filename=\"receipts-"+user.AccountID+".csv\"")
+		c.Header("Content-Type", "text/csv")
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write([]string{"created", "receiptId", "type", "offerId", "amount", "runningTotal", "items", "relatedAccountId", "relatedReceiptId", "note"})
+		for i, receipt := range receipts {
+			items := []string{}
+			for _, item := range receipt.Items {
+				items = append(items, item.TemplateID)
+			}
+			_ = w.Write([]string{
+				receipt.Created.UTC().Format(time.RFC3339),
+				receipt.ReceiptID,
+				string(receipt.Type),
+				receipt.OfferID,
+				strconv.Itoa(receipt.Amount),
+				strconv.Itoa(balances[i]),
+				strings.Join(items, ";"),
+				receipt.RelatedAccountID,
+				receipt.RelatedReceiptID,
+				receipt.Note,
+			})
+		}
+		w.Flush()
+		return
+	}
+
+	entries := make([]gin.H, len(receipts))
+	for i, receipt := range receipts {
+		entries[i] = gin.H{
+			"receipt":      receipt,
+			"runningTotal": balances[i],
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"accountId":      user.AccountID,
+		"username":       user.Username,
+		"currentBalance": currentBalance,
+		"totalSpent":     spent,
+		"totalGained":    gained,
+		"net":            running,
+		"receipts":       entries,
+	})
+}
@@ -0,0 +1,201 @@
+package api
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const shopDateLayout = "2006-01-02"
+
+func AddShopApiRoute(router *gin.Engine) {
+	router.GET("/api/venturebackend/shop", PreviewShop)
+	router.GET("/api/venturebackend/shop/history", GetShopHistory)
+	router.POST("/api/venturebackend/shop/schedule", ScheduleShop)
+	router.GET("/api/venturebackend/shop/unschedule", UnscheduleShop)
+}
+
+// parseShopDate reads a YYYY-MM-DD query parameter, defaulting to today.
+func parseShopDate(c *gin.Context, name string, def time.Time) (time.Time, bool) {
+	raw := c.Query(name)
+	if raw == "" {
+		return def.UTC().Truncate(24 * time.Hour), true
+	}
+	day, err := time.Parse(shopDateLayout, raw)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": name + " must be a date formatted YYYY-MM-DD."})
+		return time.Time{}, false
+	}
+	return day, true
+}
+
+// PreviewShop shows the storefront catalog a day runs, or ran, and where it
+// comes from.
+func PreviewShop(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	day, ok := parseShopDate(c, "date", time.Now())
+	if !ok {
+		return
+	}
+
+	shop := utils.ShopForDate(day)
+	if shop == nil && day.Before(time.Now().UTC().Truncate(24*time.Hour)) {
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "No shop was stored for that date."})
+		return
+	}
+	source := "catalog_config"
+	if shop != nil {
+		source = shop.Source
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"date":    day.Format(shopDateLayout),
+		"source":  source,
+		"shop":    shop,
+		"ends":    utils.ShopEnd(day).Format(time.RFC3339),
+		"catalog": utils.GetItemShopForDate(day),
+	})
+}
+
+// GetShopHistory lists the stored and scheduled shops between since and
+// until, the last 30 days by default.
+func GetShopHistory(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	until, ok := parseShopDate(c, "until", time.Now())
+	if !ok {
+		return
+	}
+	since, ok := parseShopDate(c, "since", until.AddDate(0, 0, -30))
+	if !ok {
+		return
+	}
+	if since.After(until) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "since must not be after until."})
+		return
+	}
+
+	shops, err := utils.FindShopRotations(since.Format(shopDateLayout), until.Format(shopDateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to read shops."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"since": since.Format(shopDateLayout),
+		"until": until.Format(shopDateLayout),
+		"shops": shops,
+	})
+}
+
+type scheduleShopRequest struct {
+	Date     string                    `json:"date"`
+	Days     int                       `json:"days"`
+	Daily    []models.ShopRotationItem `json:"daily"`
+	Featured []models.ShopRotationItem `json:"featured"`
+}
+
+// ScheduleShop sets the catalog that runs from a date, today or later, for
+// days days (1 by default), replacing what was rotated or scheduled.
+func ScheduleShop(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	var body scheduleShopRequest
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Invalid request body."})
+		return
+	}
+
+	day, err := time.Parse(shopDateLayout, body.Date)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "date must be a date formatted YYYY-MM-DD."})
+		return
+	}
+	if day.Before(time.Now().UTC().Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Past shops can not be changed."})
+		return
+	}
+	if body.Days == 0 {
+		body.Days = 1
+	}
+	if body.Days < 1 || body.Days > 28 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "days must be between 1 and 28."})
+		return
+	}
+	if len(body.Daily)+len(body.Featured) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "The shop has no items."})
+		return
+	}
+	for _, item := range append(append([]models.ShopRotationItem{}, body.Daily...), body.Featured...) {
+		if !strings.Contains(item.TemplateID, ":") || item.Price < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Invalid item " + strconv.Quote(item.TemplateID) + "."})
+			return
+		}
+		switch {
+		case item.CurrencyType == "", strings.EqualFold(item.CurrencyType, "MtxCurrency"):
+		case strings.EqualFold(item.CurrencyType, "GameItem") && strings.Contains(item.CurrencySubType, ":"):
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Invalid currency for item " + strconv.Quote(item.TemplateID) + "."})
+			return
+		}
+	}
+
+	scheduledBy := c.Query("admin")
+	if scheduledBy == "" {
+		scheduledBy = "[Administrator]"
+	}
+
+	shop := &models.ShopRotation{
+		Date:        day.Format(shopDateLayout),
+		Until:       day.AddDate(0, 0, body.Days).Format(shopDateLayout),
+		Source:      models.ShopSourceScheduled,
+		ScheduledBy: scheduledBy,
+		Daily:       body.Daily,
+		Featured:    body.Featured,
+		Created:     time.Now().UTC(),
+	}
+	if err := utils.ScheduleShop(shop); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to schedule shop."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shop scheduled.", "shop": shop})
+}
+
+// UnscheduleShop removes a scheduled shop that hasn't started yet, so the day
+// gets a rotated shop again.
+func UnscheduleShop(c *gin.Context) {
+	if !authorizeApiRequest(c) {
+		return
+	}
+
+	day, err := time.Parse(shopDateLayout, c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "Missing or invalid date."})
+		return
+	}
+
+	removed, err := utils.UnscheduleShop(day.Format(shopDateLayout))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to unschedule shop."})
+		return
+	}
+	if !removed {
+		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "No upcoming scheduled shop on that date."})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Shop unscheduled."})
+}
@@ -1,15 +1,14 @@
 package api
 
 import (
+	"VentureBackend/static/models"
 	"VentureBackend/utils"
-	"context"
 	"net/http"
 	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 func AddUmbrellaApiRoute(router *gin.Engine) {
@@ -17,11 +16,9 @@ func AddUmbrellaApiRoute(router *gin.Engine) {
 }
 
 func GetUmbrella(c *gin.Context) {
-	apikey := c.Query("apikey")
 	username := c.Query("username")
 
-	if apikey == "" || apikey != os.Getenv("bApiKey") {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing API key."})
+	if !authorizeApiRequest(c) {
 		return
 	}
 
@@ -36,82 +33,62 @@ func GetUmbrella(c *gin.Context) {
 		return
 	}
 
-	profileDoc, err := utils.FindProfileByAccountID(user.AccountID)
-	if err != nil || profileDoc == nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Profile not found."})
-		return
-	}
-
-	athena, ok := profileDoc.Profiles["athena"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Athena profile not found."})
-		return
-	}
-
-	commonCore, ok := profileDoc.Profiles["common_core"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Common core profile not found."})
-		return
-	}
-
 	cosmeticId := os.Getenv("UMBRELLA")
-
-	items := athena["items"].(map[string]interface{})
-	if _, exists := items[cosmeticId]; exists {
-		c.JSON(http.StatusBadRequest, gin.H{"code": "400", "error": "User already owns this cosmetic."})
-		return
-	}
-
 	purchaseID := uuid.New().String()
-	lootList := []map[string]interface{}{
-		{
-			"itemType": cosmeticId,
-			"itemGuid": cosmeticId,
-			"quantity": 1,
-		},
-	}
 
-	items[cosmeticId] = map[string]interface{}{
-		"templateId": cosmeticId,
-		"attributes": map[string]interface{}{
-			"creation_time":   time.Now().UTC().Format(time.RFC3339),
-			"max_level_bonus": 0,
-			"level":           1,
-			"item_seen":       false,
-		},
-		"quantity": 1,
-	}
-
-	commonItems := commonCore["items"].(map[string]interface{})
-	commonItems[purchaseID] = map[string]interface{}{
-		"templateId": "GiftBox:GB_MakeGood",
-		"attributes": map[string]interface{}{
-			"fromAccountId": "[Administrator]",
-			"lootList":      lootList,
-			"params": map[string]interface{}{
-				"userMessage": "Thanks For Playing Razer Hosting!",
+	var athena map[string]interface{}
+	_, ok := updateProfiles(c, user.AccountID, func(doc *models.Profiles) ([]string, error) {
+		var ok bool
+		athena, ok = doc.Profiles["athena"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Athena profile not found."})
+		}
+
+		commonCore, ok := doc.Profiles["common_core"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Common core profile not found."})
+		}
+
+		items := athena["items"].(map[string]interface{})
+		if _, exists := items[cosmeticId]; exists {
+			return nil, abortProfile(http.StatusBadRequest, gin.H{"code": "400", "error": "User already owns this cosmetic."})
+		}
+
+		lootList := []map[string]interface{}{
+			{
+				"itemType": cosmeticId,
+				"itemGuid": cosmeticId,
+				"quantity": 1,
 			},
-			"giftedOn": time.Now().UTC().Format(time.RFC3339),
-		},
-		"quantity": 1,
-	}
-
-	athena["rvn"] = toInt(athena["rvn"]) + 1
-	athena["commandRevision"] = toInt(athena["commandRevision"]) + 1
-	commonCore["rvn"] = toInt(commonCore["rvn"]) + 1
-	commonCore["commandRevision"] = toInt(commonCore["commandRevision"]) + 1
-
-	filter := bson.M{"accountId": user.AccountID}
-	update := bson.M{
-		"$set": bson.M{
-			"profiles.athena":      athena,
-			"profiles.common_core": commonCore,
-		},
-	}
-
-	_, err = utils.ProfileCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to update profile in DB."})
+		}
+
+		items[cosmeticId] = map[string]interface{}{
+			"templateId": cosmeticId,
+			"attributes": map[string]interface{}{
+				"creation_time":   time.Now().UTC().Format(time.RFC3339),
+				"max_level_bonus": 0,
+				"level":           1,
+				"item_seen":       false,
+			},
+			"quantity": 1,
+		}
+
+		commonItems := commonCore["items"].(map[string]interface{})
+		commonItems[purchaseID] = map[string]interface{}{
+			"templateId": "GiftBox:GB_MakeGood",
+			"attributes": map[string]interface{}{
+				"fromAccountId": "[Administrator]",
+				"lootList":      lootList,
+				"params": map[string]interface{}{
+					"userMessage": "Thanks For Playing Razer Hosting!",
+				},
+				"giftedOn": time.Now().UTC().Format(time.RFC3339),
+			},
+			"quantity": 1,
+		}
+		return []string{"athena", "common_core"}, nil
+	})
+	if !ok {
 		return
 	}
 
@@ -1,8 +1,8 @@
 package api
 
 import (
+	"VentureBackend/static/models"
 	"VentureBackend/utils"
-	"context"
 	"encoding/json"
 	"net/http"
 	"os"
@@ -10,7 +10,6 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 func AddVbucksApiRoute(router *gin.Engine) {
@@ -18,12 +17,10 @@ func AddVbucksApiRoute(router *gin.Engine) {
 }
 
 func GetVbucks(c *gin.Context) {
-	apikey := c.Query("apikey")
 	username := c.Query("username")
 	reason := c.Query("reason")
 
-	if apikey == "" || apikey != os.Getenv("bApiKey") {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing API key."})
+	if !authorizeApiRequest(c) {
 		return
 	}
 
@@ -59,49 +56,60 @@ func GetVbucks(c *gin.Context) {
 		return
 	}
 
-	profileDoc, err := utils.FindProfileByAccountID(user.AccountID)
-	if err != nil || profileDoc == nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Profile not found."})
-		return
-	}
+	var commonCore map[string]interface{}
+	var newQuantity int
+	var purchaseID string
+	_, ok = updateProfiles(c, user.AccountID, func(doc *models.Profiles) ([]string, error) {
+		var ok bool
+		commonCore, ok = doc.Profiles["common_core"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Common core profile not found."})
+		}
 
-	commonCore, ok := profileDoc.Profiles["common_core"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Common core profile not found."})
-		return
-	}
+		items := commonCore["items"].(map[string]interface{})
+		mtx, ok := items["Currency:MtxPurchased"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "V-Bucks item missing."})
+		}
 
-	items := commonCore["items"].(map[string]interface{})
-	mtx, ok := items["Currency:MtxPurchased"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "V-Bucks item missing."})
-		return
-	}
+		currentQuantity := toInt(mtx["quantity"])
+		newQuantity = currentQuantity + addValue
+		mtx["quantity"] = newQuantity
 
-	currentQuantity := int(mtx["quantity"].(float64))
-	newQuantity := currentQuantity + addValue
-	mtx["quantity"] = newQuantity
-
-	purchaseID := utils.GenerateRandomID()
-	items[purchaseID] = map[string]interface{}{
-		"templateId": "GiftBox:GB_MakeGood",
-		"attributes": map[string]interface{}{
-			"fromAccountId": "[Administrator]",
-			"lootList": []map[string]interface{}{
-				{
-					"itemType": "Currency:MtxGiveaway",
-					"itemGuid": "Currency:MtxGiveaway",
-					"quantity": addValue,
+		purchaseID = utils.GenerateRandomID()
+		items[purchaseID] = map[string]interface{}{
+			"templateId": "GiftBox:GB_MakeGood",
+			"attributes": map[string]interface{}{
+				"fromAccountId": "[Administrator]",
+				"lootList": []map[string]interface{}{
+					{
+						"itemType": "Currency:MtxGiveaway",
+						"itemGuid": "Currency:MtxGiveaway",
+						"quantity": addValue,
+					},
 				},
+				"params": map[string]interface{}{
+					"userMessage": "Thanks For Playing Razer Hosting!",
+				},
+				"giftedOn": time.Now().UTC().Format(time.RFC3339),
 			},
-			"params": map[string]interface{}{
-				"userMessage": "Thanks For Playing Razer Hosting!",
-			},
-			"giftedOn": time.Now().UTC().Format(time.RFC3339),
-		},
-		"quantity": 1,
+			"quantity": 1,
+		}
+		return []string{"common_core"}, nil
+	})
+	if !ok {
+		return
 	}
 
+	utils.RecordReceipt(models.Receipt{
+		ReceiptID:    purchaseID,
+		AccountID:    user.AccountID,
+		Type:         models.ReceiptGrant,
+		CurrencyType: "MtxCurrency",
+		Amount:       addValue,
+		Note:         reason,
+	})
+
 	applyProfileChanges := []map[string]interface{}{
 		{
 			"changeType": "itemQuantityChanged",
@@ -115,18 +123,6 @@ func GetVbucks(c *gin.Context) {
 		},
 	}
 
-	commonCore["rvn"] = int(commonCore["rvn"].(float64)) + 1
-	commonCore["commandRevision"] = int(commonCore["commandRevision"].(float64)) + 1
-
-	filter := bson.M{"accountId": user.AccountID}
-	update := bson.M{"$set": bson.M{"profiles.common_core": commonCore}}
-
-	_, err = utils.ProfileCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to update profile in DB."})
-		return
-	}
-
 	c.JSON(http.StatusOK, gin.H{
 		"profileRevision":        commonCore["rvn"],
 		"profileCommandRevision": commonCore["commandRevision"],
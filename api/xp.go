@@ -1,16 +1,15 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
 	"net/http"
 	"os"
 	"strings"
 
+	"VentureBackend/static/models"
 	"VentureBackend/utils"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 func AddXPApiRoute(router *gin.Engine) {
@@ -60,12 +59,10 @@ func toFloat64(val interface{}) float64 {
 }
 
 func GetXP(c *gin.Context) {
-	apikey := c.Query("apikey")
 	username := c.Query("username")
 	reason := c.Query("reason")
 
-	if apikey == "" || apikey != os.Getenv("bApiKey") {
-		c.JSON(http.StatusUnauthorized, gin.H{"code": "401", "error": "Invalid or missing API key."})
+	if !authorizeApiRequest(c) {
 		return
 	}
 
@@ -101,57 +98,44 @@ func GetXP(c *gin.Context) {
 		return
 	}
 
-	profileDoc, err := utils.FindProfileByAccountID(user.AccountID)
-	if err != nil || profileDoc == nil {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Profile not found."})
-		return
-	}
-
-	athena, ok := profileDoc.Profiles["athena"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{"code": "404", "error": "Athena profile not found."})
-		return
-	}
-
-	stats, ok := athena["stats"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Invalid stats structure."})
-		return
-	}
+	var athena, attributes map[string]interface{}
+	_, ok = updateProfiles(c, user.AccountID, func(doc *models.Profiles) ([]string, error) {
+		var ok bool
+		athena, ok = doc.Profiles["athena"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusNotFound, gin.H{"code": "404", "error": "Athena profile not found."})
+		}
 
-	attributes, ok := stats["attributes"].(map[string]interface{})
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Invalid attributes structure."})
-		return
-	}
+		stats, ok := athena["stats"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusInternalServerError, gin.H{"code": "500", "error": "Invalid stats structure."})
+		}
 
-	level := toInt(attributes["level"])
-	if level >= 100 {
-		c.JSON(http.StatusBadRequest, gin.H{"message": "User reached level 100."})
-		return
-	}
+		attributes, ok = stats["attributes"].(map[string]interface{})
+		if !ok {
+			return nil, abortProfile(http.StatusInternalServerError, gin.H{"code": "500", "error": "Invalid attributes structure."})
+		}
 
-	finalXP := float64(baseXP)
-	if boost := toFloat64(attributes["season_match_boost"]); boost > 0 {
-		multiplier := 1 + boost/100
-		finalXP *= multiplier
-	}
+		level := toInt(attributes["level"])
+		if level >= 100 {
+			return nil, abortProfile(http.StatusBadRequest, gin.H{"message": "User reached level 100."})
+		}
 
-	attributes["xp"] = toFloat64(attributes["xp"]) + finalXP
+		finalXP := float64(baseXP)
+		if boost := toFloat64(attributes["season_match_boost"]); boost > 0 {
+			multiplier := 1 + boost/100
+			finalXP *= multiplier
+		}
 
-	athena["rvn"] = toInt(athena["rvn"]) + 1
-	athena["commandRevision"] = toInt(athena["commandRevision"]) + 1
+		attributes["xp"] = toFloat64(attributes["xp"]) + finalXP
 
-	filter := bson.M{"accountId": user.AccountID}
-	update := bson.M{"$set": bson.M{"profiles.athena": athena}}
-	_, err = utils.ProfileCollection.UpdateOne(context.Background(), filter, update)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": "500", "error": "Failed to update profile in DB."})
+		utils.CheckAndLevelUp(doc)
+		return []string{"athena", "common_core"}, nil
+	})
+	if !ok {
 		return
 	}
 
-	utils.CheckAndLevelUp(profileDoc)
-
 	c.JSON(http.StatusOK, gin.H{
 		"profileRevision":        athena["rvn"],
 		"profileCommandRevision": athena["commandRevision"],
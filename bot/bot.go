@@ -14,11 +14,14 @@ var commandList = []registry.Command{
 	user.RegisterCommand{},
 	user.DetailsCommand{},
 	user.MobileLoginCommand{},
+	user.TwoFactorCommand{},
+	user.ChangeNameCommand{},
 	admin.DeleteUserCommand{},
 	admin.FullLockerCommand{},
 	admin.BanCommand{},
 	admin.UnbanCommand{},
 	admin.HostAccCommand{},
+	admin.ClearLockoutCommand{},
 }
 
 func InitBot() {
@@ -2,10 +2,15 @@ package admin
 
 import (
 	"context"
+	"strconv"
+	"strings"
+	"time"
 
 	registry "VentureBackend/bot/commands"
+	"VentureBackend/static/models"
 	"VentureBackend/static/tokens"
 	"VentureBackend/utils"
+	"VentureBackend/ws/matchmaker"
 	"VentureBackend/ws/xmpp"
 
 	"github.com/bwmarrin/discordgo"
@@ -24,6 +29,36 @@ func (BanCommand) Data() *discordgo.ApplicationCommand {
 				Description: "Target username.",
 				Required:    true,
 			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "type",
+				Description: "What to ban, defaults to the account. IP and HWID use the last login of the user.",
+				Required:    false,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "account", Value: string(models.BanAccount)},
+					{Name: "ip", Value: string(models.BanIP)},
+					{Name: "hwid", Value: string(models.BanHWID)},
+					{Name: "all", Value: "all"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "reason",
+				Description: "Reason shown to the player.",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "hours",
+				Description: "Ban length in hours, leave empty for a permanent ban.",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "appeal",
+				Description: "Appeal note stored with the ban.",
+				Required:    false,
+			},
 		},
 		DefaultPermission: &perms,
 	}
@@ -35,7 +70,26 @@ func (BanCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate)
 		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
 	})
 
-	username := i.ApplicationCommandData().Options[0].StringValue()
+	var username, banType, reason, appeal string
+	var hours int64
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "username":
+			username = opt.StringValue()
+		case "type":
+			banType = opt.StringValue()
+		case "reason":
+			reason = opt.StringValue()
+		case "hours":
+			hours = opt.IntValue()
+		case "appeal":
+			appeal = opt.StringValue()
+		}
+	}
+	if banType == "" {
+		banType = string(models.BanAccount)
+	}
+
 	if username == "" {
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: "Invalid username.",
@@ -53,38 +107,84 @@ func (BanCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate)
 		return
 	}
 
-	if targetUser.Banned {
-		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "This account is already banned.",
-			Flags:   discordgo.MessageFlagsEphemeral,
+	var expiresAt *time.Time
+	if hours > 0 {
+		t := time.Now().UTC().Add(time.Duration(hours) * time.Hour)
+		expiresAt = &t
+	}
+
+	targets := map[models.BanType]string{}
+	switch banType {
+	case string(models.BanAccount):
+		targets[models.BanAccount] = targetUser.AccountID
+	case string(models.BanIP):
+		targets[models.BanIP] = targetUser.LastIP
+	case string(models.BanHWID):
+		targets[models.BanHWID] = targetUser.LastHWID
+	case "all":
+		targets[models.BanAccount] = targetUser.AccountID
+		targets[models.BanIP] = targetUser.LastIP
+		targets[models.BanHWID] = targetUser.LastHWID
+	}
+
+	issuer := ""
+	if i.Member != nil && i.Member.User != nil {
+		issuer = i.Member.User.Username
+	}
+
+	banned := []string{}
+	for t, value := range targets {
+		if value == "" {
+			continue
+		}
+		err := utils.CreateBan(models.Ban{
+			Type:       t,
+			Value:      value,
+			AccountID:  targetUser.AccountID,
+			Reason:     reason,
+			IssuedBy:   issuer,
+			ExpiresAt:  expiresAt,
+			AppealNote: appeal,
 		})
-		return
+		if err != nil {
+			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: "Failed to ban the user due to a database error.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			})
+			return
+		}
+		banned = append(banned, string(t))
 	}
 
-	_, err = utils.UserCollection.UpdateOne(context.Background(),
-		map[string]interface{}{"accountId": targetUser.AccountID},
-		map[string]interface{}{"$set": map[string]interface{}{"banned": true}},
-	)
-	if err != nil {
+	if len(banned) == 0 {
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-			Content: "Failed to ban the user due to a database error.",
+			Content: "No known " + banType + " for this user, they have not logged in yet.",
 			Flags:   discordgo.MessageFlagsEphemeral,
 		})
 		return
 	}
 
+	if expiresAt == nil && targets[models.BanAccount] != "" {
+		_, err = utils.UserCollection.UpdateOne(context.Background(),
+			map[string]interface{}{"accountId": targetUser.AccountID},
+			map[string]interface{}{"$set": map[string]interface{}{"banned": true}},
+		)
+		if err != nil {
+			utils.Discord.Log("Failed to flag banned user:", err)
+		}
+	}
+
 	tokenList := tokens.GetTokensByAccountID(targetUser.AccountID)
 	go tokens.RemoveTokens(tokenList)
+	xmpp.DisconnectTokens(tokenList)
+	matchmaker.DisconnectAccount(targetUser.AccountID)
 
-	for _, client := range xmpp.Clients {
-		if client.AccountId == targetUser.AccountID {
-			client.Conn.Close()
-			break
-		}
+	length := "permanently"
+	if expiresAt != nil {
+		length = "for " + strconv.FormatInt(hours, 10) + " hours"
 	}
-
 	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
-		Content: "Successfully banned **" + targetUser.Username + "**",
+		Content: "Successfully banned **" + targetUser.Username + "** " + length + " (" + strings.Join(banned, ", ") + ")",
 		Flags:   discordgo.MessageFlagsEphemeral,
 	})
 }
@@ -0,0 +1,93 @@
+package admin
+
+import (
+	registry "VentureBackend/bot/commands"
+	"VentureBackend/utils"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ClearLockoutCommand struct{}
+
+func (ClearLockoutCommand) Data() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "clearlockout",
+		Description: "Clear the failed login lockout of a user or IP (admin only).",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "username",
+				Description: "Target username.",
+				Required:    false,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "ip",
+				Description: "Target IP address.",
+				Required:    false,
+			},
+		},
+		DefaultPermission: &perms,
+	}
+}
+
+func (ClearLockoutCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	var username, ip string
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "username":
+			username = opt.StringValue()
+		case "ip":
+			ip = opt.StringValue()
+		}
+	}
+
+	if username == "" && ip == "" {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Provide a username or an IP.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	cleared := false
+	if ip != "" {
+		cleared = utils.ClearLoginLockout("", ip) || cleared
+	}
+
+	if username != "" {
+		targetUser, err := utils.FindUserByUsername(username)
+		if err != nil || targetUser == nil {
+			s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+				Content: "The account username you entered does not exist.",
+				Flags:   discordgo.MessageFlagsEphemeral,
+			})
+			return
+		}
+
+		cleared = utils.ClearLoginLockout(targetUser.Email, "") || cleared
+		if mobileUser, err := utils.FindMobileByAccountID(targetUser.AccountID); err == nil && mobileUser != nil {
+			cleared = utils.ClearLoginLockout(mobileUser.Email, "") || cleared
+		}
+	}
+
+	if !cleared {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "There was no lockout to clear.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content: "Successfully cleared the login lockout.",
+		Flags:   discordgo.MessageFlagsEphemeral,
+	})
+}
+
+var _ registry.Command = (*ClearLockoutCommand)(nil)
@@ -53,7 +53,16 @@ func (UnbanCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate
 		return
 	}
 
-	if !targetUser.Banned {
+	lifted, err := utils.LiftBans(targetUser.AccountID)
+	if err != nil {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: "Failed to unban the user due to a database error.",
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+		return
+	}
+
+	if !targetUser.Banned && lifted == 0 {
 		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
 			Content: "This account is already unbanned.",
 			Flags:   discordgo.MessageFlagsEphemeral,
@@ -0,0 +1,72 @@
+package user
+
+import (
+	registry "VentureBackend/bot/commands"
+	"VentureBackend/utils"
+	"VentureBackend/ws/xmpp"
+	"errors"
+	"strconv"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type ChangeNameCommand struct{}
+
+func (ChangeNameCommand) Data() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "changename",
+		Description: "Changes the display name of your account.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "username",
+				Description: "Your new display name",
+				Required:    true,
+			},
+		},
+	}
+}
+
+func (ChangeNameCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	reply := func(content string) {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+
+	username := i.ApplicationCommandData().Options[0].StringValue()
+
+	user, err := utils.FindUserByDiscordId(i.Member.User.ID)
+	if err != nil || user == nil {
+		reply("You don't have a registered account.")
+		return
+	}
+
+	err = utils.ChangeDisplayName(user, username, "discord:"+i.Member.User.ID)
+	var cooldown utils.DisplayNameCooldownError
+	switch {
+	case err == nil:
+		xmpp.RefreshDisplayName(user.AccountID, user.Username)
+		reply("Your display name is now **" + user.Username + "**")
+	case errors.As(err, &cooldown):
+		reply("You can change your display name again <t:" + strconv.FormatInt(cooldown.NextChange.Unix(), 10) + ":R>.")
+	case errors.Is(err, utils.ErrDisplayNameTaken):
+		reply("That display name is already taken.")
+	case errors.Is(err, utils.ErrDisplayNameChanged):
+		reply("Your display name was just changed elsewhere, try again.")
+	case errors.Is(err, utils.ErrDisplayNameUnchanged):
+		reply("That is already your display name.")
+	case errors.Is(err, utils.ErrDisplayNameInvalid):
+		reply("Display names must be 3 to 24 characters long and can't start or end with a space.")
+	default:
+		reply("Failed to change your display name due to a database error.")
+	}
+}
+
+var _ registry.Command = (*ChangeNameCommand)(nil)
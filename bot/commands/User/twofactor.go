@@ -0,0 +1,172 @@
+package user
+
+import (
+	registry "VentureBackend/bot/commands"
+	"VentureBackend/utils"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type TwoFactorCommand struct{}
+
+func (TwoFactorCommand) Data() *discordgo.ApplicationCommand {
+	return &discordgo.ApplicationCommand{
+		Name:        "twofactor",
+		Description: "Set up or manage two-factor authentication for your account.",
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "action",
+				Description: "What to do.",
+				Required:    true,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{Name: "enable", Value: "enable"},
+					{Name: "confirm", Value: "confirm"},
+					{Name: "disable", Value: "disable"},
+					{Name: "recovery", Value: "recovery"},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "code",
+				Description: "Code from your authenticator app (or a recovery code to disable).",
+				Required:    false,
+			},
+		},
+	}
+}
+
+func (TwoFactorCommand) Execute(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: discordgo.MessageFlagsEphemeral},
+	})
+
+	reply := func(content string) {
+		s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		})
+	}
+
+	var action, code string
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "action":
+			action = opt.StringValue()
+		case "code":
+			code = strings.TrimSpace(opt.StringValue())
+		}
+	}
+
+	user, err := utils.FindUserByDiscordId(i.Member.User.ID)
+	if err != nil || user == nil {
+		reply("You don't have a registered account.")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	filter := bson.M{"accountId": user.AccountID}
+
+	switch action {
+	case "enable":
+		if user.TwoFactor.Enabled {
+			reply("Two-factor authentication is already enabled.")
+			return
+		}
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			reply("Failed to generate a secret, try again later.")
+			return
+		}
+		_, err = utils.UserCollection.UpdateOne(ctx, filter,
+			bson.M{"$set": bson.M{"twoFactor.pendingSecret": secret}})
+		if err != nil {
+			reply("Failed to start enrolment due to a database error.")
+			return
+		}
+		reply("Add this key to your authenticator app, then run `/twofactor confirm` with the code it shows.\n" +
+			"Key: ```" + secret + "```\n" +
+			"Link: ```" + utils.TOTPProvisioningURI(secret, user.Username) + "```")
+
+	case "confirm":
+		if user.TwoFactor.Enabled {
+			reply("Two-factor authentication is already enabled.")
+			return
+		}
+		if user.TwoFactor.PendingSecret == "" {
+			reply("Run `/twofactor enable` first.")
+			return
+		}
+		if !utils.VerifyTOTP(user.TwoFactor.PendingSecret, code, user.AccountID) {
+			reply("That code is not valid.")
+			return
+		}
+		codes, hashes, err := utils.GenerateRecoveryCodes(10)
+		if err != nil {
+			reply("Failed to generate recovery codes, try again later.")
+			return
+		}
+		_, err = utils.UserCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{
+			"twoFactor.enabled":       true,
+			"twoFactor.secret":        user.TwoFactor.PendingSecret,
+			"twoFactor.pendingSecret": "",
+			"twoFactor.recoveryCodes": hashes,
+			"twoFactor.enabledAt":     time.Now().UTC(),
+		}})
+		if err != nil {
+			reply("Failed to enable two-factor authentication due to a database error.")
+			return
+		}
+		reply("Two-factor authentication is now enabled. Keep these recovery codes somewhere safe, each works once:\n```" +
+			strings.Join(codes, "\n") + "```")
+
+	case "disable":
+		if !user.TwoFactor.Enabled {
+			reply("Two-factor authentication is not enabled.")
+			return
+		}
+		if !utils.VerifyUserTwoFactor(user, code) {
+			reply("That code is not valid.")
+			return
+		}
+		_, err = utils.UserCollection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"twoFactor": ""}})
+		if err != nil {
+			reply("Failed to disable two-factor authentication due to a database error.")
+			return
+		}
+		reply("Two-factor authentication has been disabled.")
+
+	case "recovery":
+		if !user.TwoFactor.Enabled {
+			reply("Two-factor authentication is not enabled.")
+			return
+		}
+		if !utils.VerifyTOTP(user.TwoFactor.Secret, code, user.AccountID) {
+			reply("That code is not valid.")
+			return
+		}
+		codes, hashes, err := utils.GenerateRecoveryCodes(10)
+		if err != nil {
+			reply("Failed to generate recovery codes, try again later.")
+			return
+		}
+		_, err = utils.UserCollection.UpdateOne(ctx, filter,
+			bson.M{"$set": bson.M{"twoFactor.recoveryCodes": hashes}})
+		if err != nil {
+			reply("Failed to replace recovery codes due to a database error.")
+			return
+		}
+		reply("Your old recovery codes no longer work. New codes:\n```" + strings.Join(codes, "\n") + "```")
+
+	default:
+		reply("Unknown action.")
+	}
+}
+
+var _ registry.Command = (*TwoFactorCommand)(nil)
@@ -4,6 +4,7 @@ import (
 	"VentureBackend/api"
 	discord "VentureBackend/bot"
 	"VentureBackend/routes"
+	"VentureBackend/static/tokens"
 	"VentureBackend/utils"
 	"VentureBackend/ws/matchmaker"
 	"VentureBackend/ws/xmpp"
@@ -27,6 +28,39 @@ func main() {
 
 	utils.Backend.Logf("Starting server on port %s...", port)
 	utils.InitMongoDB()
+	if err := tokens.InitTokenStore(); err != nil {
+		utils.Error.Logf("Failed to initialize token store: %v", err)
+	}
+	if err := utils.InitOAuthClients(); err != nil {
+		utils.Error.Logf("Failed to initialize oauth clients: %v", err)
+	}
+	if err := utils.InitDisplayNames(); err != nil {
+		utils.Error.Logf("Failed to initialize display names: %v", err)
+	}
+	if err := utils.InitBans(); err != nil {
+		utils.Error.Logf("Failed to initialize bans: %v", err)
+	}
+	if err := utils.InitGifts(); err != nil {
+		utils.Error.Logf("Failed to initialize gifts: %v", err)
+	}
+	if err := utils.InitReceipts(); err != nil {
+		utils.Error.Logf("Failed to initialize receipts: %v", err)
+	}
+	if err := utils.InitShopRotations(); err != nil {
+		utils.Error.Logf("Failed to initialize shop rotations: %v", err)
+	}
+	if err := utils.InitPayments(); err != nil {
+		utils.Error.Logf("Failed to initialize payments: %v", err)
+	}
+	if err := utils.InitPurchaseLimits(); err != nil {
+		utils.Error.Logf("Failed to initialize purchase limits: %v", err)
+	}
+	if err := utils.InitMatchResults(); err != nil {
+		utils.Error.Logf("Failed to initialize match results: %v", err)
+	}
+	if err := utils.InitLoginGuard(); err != nil {
+		utils.Error.Logf("Failed to initialize login guard: %v", err)
+	}
 	go xmpp.InitXMPP()
 	go matchmaker.InitMatchmaker()
 	go discord.InitBot()
@@ -39,6 +73,17 @@ func main() {
 
 	r := gin.New()
 	r.Use(gin.Recovery())
+	// Only requests from these proxies may set the client IP through
+	// X-Forwarded-For or X-Real-IP.
+	proxies := utils.TrustedProxies()
+	if err := r.SetTrustedProxies(proxies); err != nil {
+		utils.Error.Logf("Invalid TRUSTED_PROXIES, trusting none: %v", err)
+		proxies = nil
+		_ = r.SetTrustedProxies(nil)
+	}
+	if err := utils.SetTrustedProxies(proxies); err != nil {
+		utils.Error.Logf("Invalid TRUSTED_PROXIES, trusting none: %v", err)
+	}
 
 	r.GET("/", func(c *gin.Context) {
 		c.String(200, "Venture Backend made by Razer (originally used (was planned to be) for Razer Hosting).")
@@ -86,4 +131,9 @@ func RegisterRoutesEndpoints(router *gin.Engine) {
 	api.AddVbucksApiRoute(router)
 	api.AddXPApiRoute(router)
 	api.AddUmbrellaApiRoute(router)
+	api.AddGiftsApiRoute(router)
+	api.AddReceiptsApiRoute(router)
+	api.AddShopApiRoute(router)
+	api.AddPaymentsApiRoute(router)
+	api.AddAuthApiRoute(router)
 }
@@ -1,22 +1,29 @@
 package routes
 
 import (
+	"context"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"VentureBackend/static/models"
 	"VentureBackend/static/tokens"
 	"VentureBackend/utils"
+	"VentureBackend/ws/matchmaker"
+	"VentureBackend/ws/xmpp"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func AddOAuthRoutes(r *gin.Engine) {
 	r.POST("/account/api/oauth/token", handleOAuthToken)
 	r.GET("/account/api/oauth/verify", tokens.VerifyToken(), handleVerify)
-	r.DELETE("/account/api/oauth/sessions/kill/:token", handleKillSessionByToken)
-	r.DELETE("/account/api/oauth/sessions/kill", handleKillSessions)
+	r.GET("/account/api/oauth/exchange", tokens.VerifyToken(), tokens.RequireScope("account:exchange"), handleCreateExchangeCode)
+	r.GET("/account/api/oauth/authorize", tokens.VerifyToken(), tokens.RequireScope("account:exchange"), handleCreateAuthorizationCode)
+	r.DELETE("/account/api/oauth/sessions/kill/:token", tokens.VerifyToken(), handleKillSessionByToken)
+	r.DELETE("/account/api/oauth/sessions/kill", tokens.VerifyToken(), handleKillSessions)
 	r.POST("/auth/v1/oauth/token", handleStaticToken)
 	r.POST("/epic/oauth/v2/token", handleOAuthTokenV2)
 }
@@ -27,6 +34,13 @@ func handleOAuthToken(c *gin.Context) {
 		Username     string `form:"username" json:"username"`
 		Password     string `form:"password" json:"password"`
 		RefreshToken string `form:"refresh_token" json:"refresh_token"`
+		ExchangeCode string `form:"exchange_code" json:"exchange_code"`
+		Code         string `form:"code" json:"code"`
+		DeviceID     string `form:"device_id" json:"device_id"`
+		AccountID    string `form:"account_id" json:"account_id"`
+		Secret       string `form:"secret" json:"secret"`
+		OTP          string `form:"otp" json:"otp"`
+		Challenge    string `form:"challenge" json:"challenge"`
 	}
 
 	if err := c.ShouldBind(&body); err != nil {
@@ -37,20 +51,24 @@ func handleOAuthToken(c *gin.Context) {
 		return
 	}
 
-	authHeader := c.GetHeader("Authorization")
-	clientDecoded, err := utils.DecodeBasicAuth(authHeader)
-	if err != nil || clientDecoded.Password == "" {
+	client := authenticateClient(c)
+	if client == nil {
+		return
+	}
+	clientID := client.ClientID
+
+	if !client.AllowsGrant(body.GrantType) {
 		utils.CreateError(c,
-			"errors.com.epicgames.common.oauth.invalid_client",
-			"Invalid or missing Authorization header.",
-			[]string{}, 400, "BadRequest", 400)
+			"errors.com.epicgames.common.oauth.unauthorized_client",
+			"Sorry your client is not allowed to use the grant type "+body.GrantType+".",
+			[]string{clientID, body.GrantType}, 1015, "unauthorized_client", 400)
 		return
 	}
-	clientID := clientDecoded.Username
 
+	var err error
 	switch body.GrantType {
 	case "client_credentials":
-		token, err := tokens.CreateClient(clientID, "client_credentials", c.ClientIP(), 4)
+		token, err := tokens.CreateClient(clientID, "client_credentials", c.ClientIP(), client.Scopes, utils.DefaultClientTokenHours)
 		if err != nil {
 			utils.CreateError(c,
 				"errors.com.epicgames.common.oauth.server_error",
@@ -61,8 +79,8 @@ func handleOAuthToken(c *gin.Context) {
 
 		c.JSON(http.StatusOK, gin.H{
 			"access_token":    token,
-			"expires_in":      int((4 * time.Hour).Seconds()),
-			"expires_at":      time.Now().Add(4 * time.Hour).Format(time.RFC3339),
+			"expires_in":      int((utils.DefaultClientTokenHours * time.Hour).Seconds()),
+			"expires_at":      time.Now().Add(utils.DefaultClientTokenHours * time.Hour).Format(time.RFC3339),
 			"token_type":      "bearer",
 			"client_id":       clientID,
 			"internal_client": true,
@@ -79,23 +97,22 @@ func handleOAuthToken(c *gin.Context) {
 			return
 		}
 
+		if lockout := utils.LoginLockout(body.Username, c.ClientIP()); lockout > 0 {
+			loginLockedError(c, lockout)
+			return
+		}
+
 		var user *models.User
 		if body.Username == "hostaccount@VentureBackend.xyz" {
 			user, err = utils.FindUserByEmail(body.Username)
 			if err != nil || !utils.VerifyPassword(body.Password, user.Password) {
-				utils.CreateError(c,
-					"errors.com.epicgames.account.invalid_account_credentials",
-					"Invalid credentials.",
-					[]string{}, 400, "BadRequest", 400)
+				invalidCredentialsError(c, utils.RecordLoginFailure(body.Username, c.ClientIP()))
 				return
 			}
 		} else {
 			mobileUser, err := utils.FindMobileUserByEmail(body.Username)
 			if err != nil || body.Password != mobileUser.Password {
-				utils.CreateError(c,
-					"errors.com.epicgames.account.invalid_account_credentials",
-					"Invalid credentials.",
-					[]string{}, 400, "BadRequest", 400)
+				invalidCredentialsError(c, utils.RecordLoginFailure(body.Username, c.ClientIP()))
 				return
 			}
 			user, err = utils.FindUserByAccountID(mobileUser.AccountID)
@@ -107,44 +124,18 @@ func handleOAuthToken(c *gin.Context) {
 				return
 			}
 		}
-
 		if !validateUser(c, user) {
 			return
 		}
 
-		accessToken, err := tokens.CreateAccess(user.AccountID, user.Username, clientID, "password", utils.GenerateDeviceID(), 8)
-		if err != nil {
-			utils.CreateError(c,
-				"errors.com.epicgames.common.oauth.server_error",
-				"Failed to create access token.",
-				[]string{}, 500, "InternalServerError", 500)
-			return
-		}
-
-		refreshToken, err := tokens.CreateRefresh(user.AccountID, user.Username, clientID, "password", utils.GenerateDeviceID(), 24)
-		if err != nil {
-			utils.CreateError(c,
-				"errors.com.epicgames.common.oauth.server_error",
-				"Failed to create refresh token.",
-				[]string{}, 500, "InternalServerError", 500)
+		// The counters are only reset once the second factor is in too.
+		if user.TwoFactor.Enabled {
+			twoFactorRequiredError(c, user, clientID)
 			return
 		}
+		utils.RecordLoginSuccess(body.Username)
 
-		c.JSON(http.StatusOK, gin.H{
-			"access_token":       accessToken,
-			"expires_in":         int((8 * time.Hour).Seconds()),
-			"expires_at":         time.Now().Add(8 * time.Hour).Format(time.RFC3339),
-			"token_type":         "bearer",
-			"refresh_token":      refreshToken,
-			"refresh_expires":    int((24 * time.Hour).Seconds()),
-			"refresh_expires_at": time.Now().Add(24 * time.Hour).Format(time.RFC3339),
-			"account_id":         user.AccountID,
-			"client_id":          clientID,
-			"displayName":        user.Username,
-			"app":                "fortnite",
-			"in_app_id":          user.AccountID,
-			"device_id":          utils.GenerateDeviceID(),
-		})
+		issueAccountTokens(c, user, client, "password", "")
 		return
 
 	case "refresh_token":
@@ -157,7 +148,7 @@ func handleOAuthToken(c *gin.Context) {
 		}
 
 		decoded, err := tokens.DecodeJWT(body.RefreshToken)
-		if err != nil || time.Now().After(decoded.ExpiresAt) {
+		if err != nil || decoded.TokenType != "r" || tokens.FindRefreshToken(body.RefreshToken) == nil {
 			utils.CreateError(c,
 				"errors.com.epicgames.account.auth_token.invalid_refresh_token",
 				"Sorry the refresh token '"+body.RefreshToken+"' is invalid.",
@@ -184,49 +175,154 @@ func handleOAuthToken(c *gin.Context) {
 			return
 		}
 
-		if user.Banned {
+		if !validateUser(c, user) {
+			return
+		}
+
+		c.Set("user", user)
+
+		issueAccountTokens(c, user, client, "refresh_token", decoded.DeviceID)
+		return
+
+	case "exchange_code":
+		if body.ExchangeCode == "" {
 			utils.CreateError(c,
-				"errors.com.epicgames.account.auth_token.invalid_refresh_token",
-				"Sorry the refresh token '"+body.RefreshToken+"' is invalid.",
-				[]string{body.RefreshToken}, 18036, "invalid_grant", 400)
+				"errors.com.epicgames.common.oauth.invalid_request",
+				"Exchange code is required.",
+				[]string{}, 400, "BadRequest", 400)
+			return
+		}
+
+		code := tokens.ConsumeExchangeCode(body.ExchangeCode)
+		if code == nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.oauth.exchange_code_not_found",
+				"Sorry the exchange code you supplied was not found. It is possible that it was no longer valid",
+				[]string{}, 18057, "invalid_grant", 400)
 			return
 		}
 
-		accessToken, err := tokens.CreateAccess(user.AccountID, user.Username, clientID, "refresh_token", utils.GenerateDeviceID(), 8)
+		user, err := utils.FindUserByAccountID(code.AccountID)
 		if err != nil {
+			user = nil
+		}
+		if !validateUser(c, user) {
+			return
+		}
+
+		issueAccountTokens(c, user, client, "exchange_code", "")
+		return
+
+	case "authorization_code":
+		if body.Code == "" {
 			utils.CreateError(c,
-				"errors.com.epicgames.common.oauth.server_error",
-				"Failed to create access token.",
-				[]string{}, 500, "InternalServerError", 500)
+				"errors.com.epicgames.common.oauth.invalid_request",
+				"Authorization code is required.",
+				[]string{}, 400, "BadRequest", 400)
 			return
 		}
 
-		newRefreshToken, err := tokens.CreateRefresh(user.AccountID, user.Username, clientID, "refresh_token", utils.GenerateDeviceID(), 24)
+		code := tokens.ConsumeAuthorizationCode(body.Code)
+		if code == nil || code.ClientID != clientID {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.oauth.authorization_code_not_found",
+				"Sorry the authorization code you supplied was not found. It is possible that it was no longer valid",
+				[]string{}, 18059, "invalid_grant", 400)
+			return
+		}
+
+		user, err := utils.FindUserByAccountID(code.AccountID)
 		if err != nil {
+			user = nil
+		}
+		if !validateUser(c, user) {
+			return
+		}
+
+		issueAccountTokens(c, user, client, "authorization_code", "")
+		return
+
+	case "otp":
+		if body.OTP == "" || body.Challenge == "" {
 			utils.CreateError(c,
-				"errors.com.epicgames.common.oauth.server_error",
-				"Failed to create refresh token.",
-				[]string{}, 500, "InternalServerError", 500)
+				"errors.com.epicgames.common.oauth.invalid_request",
+				"otp and challenge are required.",
+				[]string{}, 400, "BadRequest", 400)
 			return
 		}
 
-		c.Set("user", user)
+		// The challenge is spent by every attempt, so a wrong code sends the
+		// client back through the throttled password grant.
+		challenge := tokens.ConsumeTwoFactorChallenge(body.Challenge)
+		if challenge == nil || challenge.ClientID != clientID {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.two_factor_authentication.challenge_not_found",
+				"Sorry the two factor challenge you supplied was not found. It is possible that it was no longer valid",
+				[]string{}, 1044, "invalid_grant", 400)
+			return
+		}
 
-		c.JSON(http.StatusOK, gin.H{
-			"access_token":       accessToken,
-			"expires_in":         int((8 * time.Hour).Seconds()),
-			"expires_at":         time.Now().Add(8 * time.Hour).Format(time.RFC3339),
-			"token_type":         "bearer",
-			"refresh_token":      newRefreshToken,
-			"refresh_expires":    int((24 * time.Hour).Seconds()),
-			"refresh_expires_at": time.Now().Add(24 * time.Hour).Format(time.RFC3339),
-			"account_id":         user.AccountID,
-			"client_id":          clientID,
-			"displayName":        user.Username,
-			"app":                "fortnite",
-			"in_app_id":          user.AccountID,
-			"device_id":          utils.GenerateDeviceID(),
-		})
+		user, err := utils.FindUserByAccountID(challenge.AccountID)
+		if err != nil {
+			user = nil
+		}
+		if !validateUser(c, user) {
+			return
+		}
+
+		loginName := utils.LoginName(user)
+		if lockout := utils.LoginLockout(loginName, c.ClientIP()); lockout > 0 {
+			loginLockedError(c, lockout)
+			return
+		}
+
+		if !utils.VerifyUserTwoFactor(user, body.OTP) {
+			if lockout := utils.RecordLoginFailure(loginName, c.ClientIP()); lockout > 0 {
+				loginLockedError(c, lockout)
+				return
+			}
+			utils.CreateError(c,
+				"errors.com.epicgames.common.two_factor_authentication.verification_failed",
+				"Sorry the two factor code you supplied is invalid",
+				[]string{}, 1043, "invalid_grant", 400)
+			return
+		}
+		utils.RecordLoginSuccess(loginName)
+
+		issueAccountTokens(c, user, client, "otp", "")
+		return
+
+	case "device_auth":
+		if body.DeviceID == "" || body.AccountID == "" || body.Secret == "" {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.oauth.invalid_request",
+				"device_id, account_id and secret are required.",
+				[]string{}, 400, "BadRequest", 400)
+			return
+		}
+
+		user, err := utils.FindUserByAccountID(body.AccountID)
+		if err != nil || !verifyDeviceAuth(user, body.DeviceID, body.Secret) {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.invalid_account_credentials",
+				"Sorry the account credentials you are using are invalid",
+				[]string{}, 18031, "invalid_grant", 400)
+			return
+		}
+		if !validateUser(c, user) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = utils.UserCollection.UpdateOne(ctx,
+			bson.M{"accountId": user.AccountID, "deviceAuths.deviceId": body.DeviceID},
+			bson.M{"$set": bson.M{"deviceAuths.$.lastAccess": time.Now().UTC()}})
+		if err != nil {
+			utils.OAuth2.Log("Failed to update device auth last access:", err)
+		}
+
+		issueAccountTokens(c, user, client, "device_auth", "")
 		return
 
 	default:
@@ -237,6 +333,148 @@ func handleOAuthToken(c *gin.Context) {
 	}
 }
 
+// authenticateClient resolves the Basic auth header to a registered client and
+// writes an invalid_client error when that fails.
+func authenticateClient(c *gin.Context) *models.OAuthClient {
+	clientDecoded, err := utils.DecodeBasicAuth(c.GetHeader("Authorization"))
+	if err != nil || clientDecoded.Password == "" {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.invalid_client",
+			"It appears that your Authorization header may be invalid or not present, please verify that you are sending the correct headers.",
+			[]string{}, 1011, "invalid_client", 400)
+		return nil
+	}
+
+	client := utils.AuthenticateOAuthClient(clientDecoded.Username, clientDecoded.Password)
+	if client == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.invalid_client",
+			"It appears that your client credentials are invalid, please verify that you are sending the correct client id and secret.",
+			[]string{clientDecoded.Username}, 1011, "invalid_client", 401)
+		return nil
+	}
+	return client
+}
+
+// issueAccountTokens issues the access/refresh pair of a session. Refreshing
+// passes the session's device id so the new pair replaces the old one; every
+// other grant starts a new session.
+func issueAccountTokens(c *gin.Context, user *models.User, client *models.OAuthClient, grantType, deviceID string) {
+	clientID := client.ClientID
+	accessHours := client.AccessTokenHours
+	if accessHours <= 0 {
+		accessHours = utils.DefaultAccessTokenHours
+	}
+	refreshHours := client.RefreshTokenHours
+	if refreshHours <= 0 {
+		refreshHours = utils.DefaultRefreshTokenHours
+	}
+	accessExpiry := time.Duration(accessHours) * time.Hour
+	refreshExpiry := time.Duration(refreshHours) * time.Hour
+
+	if deviceID == "" {
+		deviceID = utils.GenerateDeviceID()
+	}
+	go utils.RecordLoginOrigin(user.AccountID, c.ClientIP(), c.GetHeader(utils.HWIDHeader))
+
+	accessToken, err := tokens.CreateAccess(user.AccountID, user.Username, clientID, grantType, deviceID, client.Scopes, accessHours)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.server_error",
+			"Failed to create access token.",
+			[]string{}, 500, "InternalServerError", 500)
+		return
+	}
+
+	refreshToken, err := tokens.CreateRefresh(user.AccountID, user.Username, clientID, grantType, deviceID, refreshHours)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.server_error",
+			"Failed to create refresh token.",
+			[]string{}, 500, "InternalServerError", 500)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":       accessToken,
+		"expires_in":         int(accessExpiry.Seconds()),
+		"expires_at":         time.Now().Add(accessExpiry).Format(time.RFC3339),
+		"token_type":         "bearer",
+		"refresh_token":      refreshToken,
+		"refresh_expires":    int(refreshExpiry.Seconds()),
+		"refresh_expires_at": time.Now().Add(refreshExpiry).Format(time.RFC3339),
+		"account_id":         user.AccountID,
+		"client_id":          clientID,
+		"scope":              client.Scopes,
+		"displayName":        user.Username,
+		"app":                "fortnite",
+		"in_app_id":          user.AccountID,
+		"device_id":          deviceID,
+	})
+}
+
+func handleCreateExchangeCode(c *gin.Context) {
+	decoded := tokens.FromContext(c)
+	if decoded == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.authentication.authentication_failed",
+			"Authentication failed.",
+			[]string{}, 1032, "Unauthorized", 401)
+		return
+	}
+
+	code, expiresAt, err := tokens.CreateExchangeCode(decoded.AccountID, decoded.ClientID)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.server_error",
+			"Failed to create exchange code.",
+			[]string{}, 1000, "InternalServerError", 500)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expiresInSeconds": int(time.Until(expiresAt).Seconds()),
+		"code":             code,
+		"creatingClientId": decoded.ClientID,
+	})
+}
+
+func handleCreateAuthorizationCode(c *gin.Context) {
+	decoded := tokens.FromContext(c)
+	if decoded == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.authentication.authentication_failed",
+			"Authentication failed.",
+			[]string{}, 1032, "Unauthorized", 401)
+		return
+	}
+
+	clientID := c.Query("clientId")
+	if clientID == "" {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.invalid_request",
+			"clientId is required.",
+			[]string{"clientId"}, 1013, "invalid_request", 400)
+		return
+	}
+
+	code, expiresAt, err := tokens.CreateAuthorizationCode(decoded.AccountID, clientID)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.server_error",
+			"Failed to create authorization code.",
+			[]string{}, 1000, "InternalServerError", 500)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"redirectUrl":       c.Query("redirectUrl"),
+		"authorizationCode": code,
+		"expiresInSeconds":  int(time.Until(expiresAt).Seconds()),
+		"sid":               nil,
+	})
+}
+
 func handleVerify(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if token == "" {
@@ -297,13 +535,119 @@ func handleVerify(c *gin.Context) {
 	})
 }
 
+// handleKillSessions revokes the caller's sessions selected by killType. A
+// session is the access/refresh pair sharing a client and device id; the
+// OTHERS variants keep the one the request was made with.
 func handleKillSessions(c *gin.Context) {
+	decoded := tokens.FromContext(c)
+	if decoded == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.authentication.authentication_failed",
+			"Authentication failed.",
+			[]string{}, 1032, "Unauthorized", 401)
+		return
+	}
+
+	killType := strings.ToUpper(c.Query("killType"))
+	var keepCurrent, sameClient bool
+	switch killType {
+	case "ALL":
+		var toRemove []string
+		for _, t := range tokens.FindSessionTokens(decoded.AccountID) {
+			toRemove = append(toRemove, t.Token)
+		}
+		tokens.RemoveTokens(toRemove)
+		xmpp.DisconnectAccount(decoded.AccountID)
+		matchmaker.DisconnectAccount(decoded.AccountID)
+		c.Status(http.StatusNoContent)
+		return
+	case "OTHERS":
+		keepCurrent = true
+	case "ALL_ACCOUNT_CLIENT":
+		sameClient = true
+	case "OTHERS_ACCOUNT_CLIENT", "OTHERS_ACCOUNT_CLIENT_SERVICE":
+		keepCurrent = true
+		sameClient = true
+	default:
+		utils.CreateError(c,
+			"errors.com.epicgames.bad_request",
+			"Required String parameter 'killType' is invalid or not present",
+			[]string{"killType"}, 1001, "parameter_invalid", http.StatusBadRequest)
+		return
+	}
+
+	kill := func(clientID, deviceID string) bool {
+		if keepCurrent && clientID == decoded.ClientID && deviceID == decoded.DeviceID {
+			return false
+		}
+		return !sameClient || clientID == decoded.ClientID
+	}
+
+	var toRemove []string
+	remaining := 0
+	for _, t := range tokens.FindSessionTokens(decoded.AccountID) {
+		if !kill(t.ClientID, t.DeviceID) {
+			remaining++
+			continue
+		}
+		toRemove = append(toRemove, t.Token)
+	}
+
+	tokens.RemoveTokens(toRemove)
+	xmpp.DisconnectSessions(decoded.AccountID, kill)
+	if remaining == 0 {
+		// Matchmaker connections are not bound to a session, so they only go
+		// away once the account has no session left.
+		matchmaker.DisconnectAccount(decoded.AccountID)
+	}
+
 	c.Status(http.StatusNoContent)
 }
 
+// handleKillSessionByToken revokes the session the given access or refresh
+// token belongs to. Callers can only kill their own sessions.
 func handleKillSessionByToken(c *gin.Context) {
+	decoded := tokens.FromContext(c)
+	if decoded == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.authentication.authentication_failed",
+			"Authentication failed.",
+			[]string{}, 1032, "Unauthorized", 401)
+		return
+	}
+
 	token := c.Param("token")
-	tokens.RemoveTokens([]string{token})
+	session := tokens.FindAccessToken(token)
+	if session == nil {
+		session = tokens.FindRefreshToken(token)
+	}
+	if session == nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.account.auth_token.unknown_oauth_session",
+			"Sorry the oauth session '"+token+"' was not found",
+			[]string{token}, 18051, "not_found", http.StatusNotFound)
+		return
+	}
+	if session.AccountID != decoded.AccountID {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.missing_permission",
+			"Sorry your login does not posses the permissions to kill this session",
+			[]string{}, 1023, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	inSession := func(clientID, deviceID string) bool {
+		return clientID == session.ClientID && deviceID == session.DeviceID
+	}
+	var toRemove []string
+	for _, t := range tokens.FindSessionTokens(session.AccountID) {
+		if inSession(t.ClientID, t.DeviceID) {
+			toRemove = append(toRemove, t.Token)
+		}
+	}
+	tokens.RemoveTokens(toRemove)
+	xmpp.DisconnectSessions(session.AccountID, inSession)
+
 	c.Status(http.StatusNoContent)
 }
 
@@ -322,24 +666,11 @@ func handleStaticToken(c *gin.Context) {
 }
 
 func handleOAuthTokenV2(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Basic ") {
-		utils.CreateError(c,
-			"errors.com.epicgames.common.oauth.invalid_client",
-			"It appears that your Authorization header may be invalid or not present, please verify that you are sending the correct headers.",
-			[]string{}, 1011, "invalid_client", 400)
+	client := authenticateClient(c)
+	if client == nil {
 		return
 	}
-
-	clientDecoded, err := utils.DecodeBasicAuth(authHeader)
-	if err != nil || clientDecoded.Password == "" {
-		utils.CreateError(c,
-			"errors.com.epicgames.common.oauth.invalid_client",
-			"It appears that your Authorization header may be invalid or not present, please verify that you are sending the correct headers.",
-			[]string{}, 1011, "invalid_client", 400)
-		return
-	}
-	clientID := clientDecoded.Username
+	clientID := client.ClientID
 
 	var body struct {
 		RefreshToken string `json:"refresh_token"`
@@ -354,25 +685,16 @@ func handleOAuthTokenV2(c *gin.Context) {
 	}
 
 	refreshToken := body.RefreshToken
-	index := tokens.FindRefreshTokenIndex(refreshToken)
-	if index == -1 {
-		utils.CreateError(c,
-			"errors.com.epicgames.account.auth_token.invalid_refresh_token",
-			"Sorry the refresh token '"+refreshToken+"' is invalid.",
-			[]string{refreshToken}, 18036, "invalid_grant", 400)
-		return
-	}
-
-	tokenData, ok := tokens.GetRefreshToken(index)
-	if !ok {
+	tokenData := tokens.FindRefreshToken(refreshToken)
+	if tokenData == nil {
 		utils.CreateError(c,
 			"errors.com.epicgames.account.auth_token.invalid_refresh_token",
 			"Sorry the refresh token '"+refreshToken+"' is invalid.",
 			[]string{refreshToken}, 18036, "invalid_grant", 400)
 		return
 	}
-	decoded, err := tokens.DecodeJWT(strings.TrimPrefix(refreshToken, "eg1~"))
-	if err != nil || time.Now().After(decoded.ExpiresAt) {
+	decoded, err := tokens.DecodeJWT(refreshToken)
+	if err != nil || decoded.TokenType != "r" || decoded.AccountID != tokenData.AccountID {
 		tokens.RemoveRefreshToken(refreshToken)
 		utils.CreateError(c,
 			"errors.com.epicgames.account.auth_token.invalid_refresh_token",
@@ -410,6 +732,70 @@ func handleOAuthTokenV2(c *gin.Context) {
 	})
 }
 
+// invalidCredentialsError reports a failed login, with the lockout it caused
+// in messageVars when the attempt tipped the counters over.
+func invalidCredentialsError(c *gin.Context, lockout time.Duration) {
+	if lockout > 0 {
+		loginLockedError(c, lockout)
+		return
+	}
+	utils.CreateError(c,
+		"errors.com.epicgames.account.invalid_account_credentials",
+		"Sorry the account credentials you are using are invalid",
+		[]string{}, 18031, "invalid_grant", 400)
+}
+
+// twoFactorRequiredError answers a correct password on a 2FA account with a
+// challenge the otp grant has to redeem.
+func twoFactorRequiredError(c *gin.Context, user *models.User, clientID string) {
+	challenge, _, err := tokens.CreateTwoFactorChallenge(user.AccountID, clientID)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.oauth.server_error",
+			"Failed to create two factor challenge.",
+			[]string{}, 500, "InternalServerError", 500)
+		return
+	}
+
+	errorCode := "errors.com.epicgames.common.two_factor_authentication.required"
+	errorMessage := "Two-Factor authentication verification is required to continue"
+	c.Header("X-Epic-Error-Name", errorCode)
+	c.Header("X-Epic-Error-Code", "1042")
+	c.JSON(http.StatusBadRequest, gin.H{
+		"errorCode":          errorCode,
+		"errorMessage":       errorMessage,
+		"messageVars":        []string{},
+		"numericErrorCode":   1042,
+		"originatingService": "any",
+		"intent":             "prod",
+		"error_description":  errorMessage,
+		"error":              "two_factor_authentication.required",
+		"challenge":          challenge,
+		"metadata": gin.H{
+			"twoFactorMethod":  "authenticator",
+			"alternateMethods": []string{"recovery_code"},
+		},
+	})
+}
+
+func loginLockedError(c *gin.Context, lockout time.Duration) {
+	seconds := int(lockout.Round(time.Second).Seconds())
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	utils.CreateError(c,
+		"errors.com.epicgames.account.invalid_account_credentials",
+		"Sorry the account credentials you are using are invalid. Too many failed attempts, try again in "+strconv.Itoa(seconds)+" seconds.",
+		[]string{strconv.Itoa(seconds)}, 18031, "invalid_grant", 400)
+}
+
+func verifyDeviceAuth(user *models.User, deviceID, secret string) bool {
+	for _, device := range user.DeviceAuths {
+		if device.DeviceID == deviceID {
+			return utils.VerifyPassword(secret, device.SecretHash)
+		}
+	}
+	return false
+}
+
 func validateUser(c *gin.Context, user *models.User) bool {
 	if user == nil {
 		utils.CreateError(c,
@@ -426,5 +812,22 @@ func validateUser(c *gin.Context, user *models.User) bool {
 		)
 		return false
 	}
+
+	ban, err := utils.FindActiveBan(user.AccountID, c.ClientIP(), c.GetHeader(utils.HWIDHeader))
+	if err != nil {
+		utils.Error.Log("Failed to check bans:", err)
+	}
+	if ban != nil {
+		expires := "never"
+		if ban.ExpiresAt != nil {
+			expires = ban.ExpiresAt.UTC().Format(time.RFC3339)
+		}
+		utils.CreateError(c,
+			"errors.com.epicgames.account.account_not_active",
+			utils.BanMessage(ban),
+			[]string{ban.Reason, expires}, -1, "BadRequest", 400,
+		)
+		return false
+	}
 	return true
 }
@@ -0,0 +1,454 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "ClaimMissionAlertRewards",
+		ProfileIDs: []string{"campaign"},
+		Request:    func() interface{} { return &claimMissionAlertRewardsRequest{} },
+		Handler:    claimMissionAlertRewards,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "OpenCardPack",
+		ProfileIDs: []string{"campaign"},
+		Request:    func() interface{} { return &openCardPackRequest{} },
+		Handler:    openCardPack,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "RecycleItem",
+		ProfileIDs: []string{"campaign"},
+		Request:    func() interface{} { return &campaignItemRequest{} },
+		Handler:    recycleItem,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "UpgradeItem",
+		ProfileIDs: []string{"campaign"},
+		Request:    func() interface{} { return &campaignItemRequest{} },
+		Handler:    upgradeItem,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "AssignWorkerToSquad",
+		ProfileIDs: []string{"campaign"},
+		Request:    func() interface{} { return &assignWorkerToSquadRequest{} },
+		Handler:    assignWorkerToSquad,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "SetHomebaseName",
+		ProfileIDs: []string{"common_public"},
+		Request:    func() interface{} { return &setHomebaseNameRequest{} },
+		Handler:    setHomebaseName,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "ClaimCollectionBookRewards",
+		ProfileIDs: []string{"campaign"},
+		Handler:    claimCollectionBookRewards,
+	})
+}
+
+var homebaseNamePattern = regexp.MustCompile(`^[\p{L}\p{N} _'-]+$`)
+
+// campaignContext opens the campaign template and profile every campaign
+// command works on.
+func campaignContext(ctx *MCPContext) (*utils.CampaignData, *models.CampaignProfile, error) {
+	data, err := utils.LoadCampaign()
+	if err != nil {
+		return nil, nil, NewMCPError("errors.com.epicgames.fortnite.campaign_unavailable",
+			"The campaign template is not available",
+			nil, 16099, http.StatusInternalServerError)
+	}
+	campaign, err := ctx.Campaign()
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, campaign, nil
+}
+
+func campaignItemNotFound(itemID string) error {
+	return NewMCPError("errors.com.epicgames.fortnite.item_not_found",
+		fmt.Sprintf("Item '%s' not found", itemID),
+		[]string{itemID}, 16006, http.StatusBadRequest)
+}
+
+// grantCampaignRewards adds rewards to the campaign profile, stacking
+// resources onto the items already held.
+func grantCampaignRewards(ctx *MCPContext, data *utils.CampaignData, items *models.ProfileItems, rewards []utils.CampaignReward) {
+	for _, reward := range rewards {
+		if reward.Quantity <= 0 {
+			continue
+		}
+		if data.Stackable(reward.TemplateID) {
+			if id := findCampaignStack(items, reward.TemplateID); id != "" {
+				stack, _ := items.Get(id)
+				stack.Quantity += reward.Quantity
+				ctx.ItemQuantityChanged("campaign", id, stack.Quantity)
+				continue
+			}
+			item := models.NewProfileItem(reward.TemplateID)
+			item.Quantity = reward.Quantity
+			id := utils.GenerateRandomID()
+			items.Set(id, item)
+			ctx.ItemAdded("campaign", id, item)
+			continue
+		}
+		for i := 0; i < reward.Quantity; i++ {
+			item := models.NewProfileItem(reward.TemplateID)
+			item.Attributes.Level = 1
+			id := utils.GenerateRandomID()
+			items.Set(id, item)
+			ctx.ItemAdded("campaign", id, item)
+		}
+	}
+}
+
+func findCampaignStack(items *models.ProfileItems, templateID string) string {
+	for _, id := range items.IDs() {
+		if strings.EqualFold(items.TemplateID(id), templateID) {
+			return id
+		}
+	}
+	return ""
+}
+
+// spendCampaignResource takes amount from the resource stack, reporting
+// false when there isn't enough.
+func spendCampaignResource(ctx *MCPContext, items *models.ProfileItems, templateID string, amount int) bool {
+	id := findCampaignStack(items, templateID)
+	if id == "" {
+		return amount <= 0
+	}
+	stack, ok := items.Get(id)
+	if !ok || stack.Quantity < amount {
+		return false
+	}
+	stack.Quantity -= amount
+	ctx.ItemQuantityChanged("campaign", id, stack.Quantity)
+	return true
+}
+
+type claimMissionAlertRewardsRequest struct {
+	MissionAlertID string `json:"missionAlertId" binding:"required"`
+}
+
+// claimMissionAlertRewards grants a mission alert's rewards once per alert
+// rotation, which ends at the next UTC midnight.
+func claimMissionAlertRewards(ctx *MCPContext, req interface{}) error {
+	body := req.(*claimMissionAlertRewardsRequest)
+
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	alert, ok := data.MissionAlerts[body.MissionAlertID]
+	if !ok {
+		return NewMCPError("errors.com.epicgames.fortnite.mission_alert_not_found",
+			fmt.Sprintf("Mission alert '%s' not found", body.MissionAlertID),
+			[]string{body.MissionAlertID}, 16044, http.StatusBadRequest)
+	}
+
+	now := time.Now().UTC()
+	record := &campaign.Stats.Attributes.MissionAlertRedemptionRecord
+	claimData := []models.MissionAlertClaim{}
+	for _, claim := range record.ClaimData {
+		evictAfter, err := time.Parse(time.RFC3339, claim.EvictClaimDataAfterUtc)
+		if err != nil || !now.Before(evictAfter) {
+			continue
+		}
+		if claim.MissionAlertID == body.MissionAlertID {
+			return NewMCPError("errors.com.epicgames.fortnite.mission_alert_claimed",
+				fmt.Sprintf("Mission alert '%s' was already claimed", body.MissionAlertID),
+				[]string{body.MissionAlertID}, 16045, http.StatusBadRequest)
+		}
+		claimData = append(claimData, claim)
+	}
+
+	grantCampaignRewards(ctx, data, &campaign.Items, alert.Rewards)
+
+	claimData = append(claimData, models.MissionAlertClaim{
+		MissionAlertID:         body.MissionAlertID,
+		RedemptionDateUtc:      now.Format(time.RFC3339),
+		EvictClaimDataAfterUtc: now.Truncate(24 * time.Hour).Add(24 * time.Hour).Format(time.RFC3339),
+	})
+	record.ClaimData = claimData
+	ctx.StatModified("campaign", "mission_alert_redemption_record", record)
+	return nil
+}
+
+type openCardPackRequest struct {
+	CardPackItemID string `json:"cardPackItemId" binding:"required"`
+	SelectionIdx   int    `json:"selectionIdx"`
+}
+
+func openCardPack(ctx *MCPContext, req interface{}) error {
+	body := req.(*openCardPackRequest)
+
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	item, ok := campaign.Items.Get(body.CardPackItemID)
+	if !ok {
+		return campaignItemNotFound(body.CardPackItemID)
+	}
+	pack, ok := data.FindCardPack(item.TemplateID)
+	if !ok || item.Quantity <= 0 {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_card_pack",
+			fmt.Sprintf("Item '%s' is not a card pack", body.CardPackItemID),
+			[]string{body.CardPackItemID}, 16046, http.StatusBadRequest)
+	}
+
+	if item.Quantity > 1 {
+		item.Quantity--
+		ctx.ItemQuantityChanged("campaign", body.CardPackItemID, item.Quantity)
+	} else {
+		campaign.Items.Delete(body.CardPackItemID)
+		ctx.ItemRemoved("campaign", body.CardPackItemID)
+	}
+
+	grantCampaignRewards(ctx, data, &campaign.Items, pack.Roll())
+	return nil
+}
+
+type campaignItemRequest struct {
+	TargetItemID string `json:"targetItemId" binding:"required"`
+}
+
+// recycleItem turns an item into its upgrade resource and adds its template
+// to the collection book the first time one is recycled.
+func recycleItem(ctx *MCPContext, req interface{}) error {
+	body := req.(*campaignItemRequest)
+
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	item, ok := campaign.Items.Get(body.TargetItemID)
+	if !ok {
+		return campaignItemNotFound(body.TargetItemID)
+	}
+	itemType, ok := data.ItemType(item.TemplateID)
+	if !ok {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_recyclable",
+			fmt.Sprintf("Item '%s' can not be recycled", body.TargetItemID),
+			[]string{body.TargetItemID}, 16047, http.StatusBadRequest)
+	}
+	if item.Attributes.Favorite {
+		return NewMCPError("errors.com.epicgames.fortnite.item_is_favorite",
+			fmt.Sprintf("Item '%s' is a favorite and can not be recycled", body.TargetItemID),
+			[]string{body.TargetItemID}, 16048, http.StatusBadRequest)
+	}
+
+	level := max(item.Attributes.Level, 1)
+	campaign.Items.Delete(body.TargetItemID)
+	ctx.ItemRemoved("campaign", body.TargetItemID)
+	grantCampaignRewards(ctx, data, &campaign.Items, []utils.CampaignReward{{
+		TemplateID: itemType.RecycleResource,
+		Quantity:   data.RecycleValue(itemType, item.TemplateID, level),
+	}})
+
+	if itemType.CollectionBook == "" {
+		return nil
+	}
+	book, err := ctx.Profile(itemType.CollectionBook)
+	if err != nil {
+		return nil
+	}
+	if findCampaignStack(&book.Items, item.TemplateID) != "" {
+		return nil
+	}
+	collected := models.NewProfileItem(item.TemplateID)
+	collected.Attributes.Level = level
+	id := utils.GenerateRandomID()
+	book.Items.Set(id, collected)
+	ctx.ItemAdded(itemType.CollectionBook, id, collected)
+
+	collectionBook := &campaign.Stats.Attributes.CollectionBook
+	collectionBook.BookXP += itemType.BookXP * data.Rarity(item.TemplateID)
+	ctx.StatModified("campaign", "collection_book", collectionBook)
+	return nil
+}
+
+func upgradeItem(ctx *MCPContext, req interface{}) error {
+	body := req.(*campaignItemRequest)
+
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	item, ok := campaign.Items.Get(body.TargetItemID)
+	if !ok {
+		return campaignItemNotFound(body.TargetItemID)
+	}
+	itemType, ok := data.ItemType(item.TemplateID)
+	if !ok {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_upgradable",
+			fmt.Sprintf("Item '%s' can not be upgraded", body.TargetItemID),
+			[]string{body.TargetItemID}, 16049, http.StatusBadRequest)
+	}
+
+	level := max(item.Attributes.Level, 1)
+	if level >= data.MaxLevel(item.TemplateID) {
+		return NewMCPError("errors.com.epicgames.fortnite.item_at_max_level",
+			fmt.Sprintf("Item '%s' is already at its maximum level", body.TargetItemID),
+			[]string{body.TargetItemID}, 16050, http.StatusBadRequest)
+	}
+	cost := data.UpgradeCost(itemType, item.TemplateID, level)
+	if !spendCampaignResource(ctx, &campaign.Items, itemType.UpgradeResource, cost) {
+		return NewMCPError("errors.com.epicgames.fortnite.insufficient_resources",
+			fmt.Sprintf("Upgrading needs %d %s", cost, itemType.UpgradeResource),
+			[]string{itemType.UpgradeResource, fmt.Sprint(cost)}, 16051, http.StatusBadRequest)
+	}
+
+	item.Attributes.Level = level + 1
+	ctx.ItemAttrChanged("campaign", body.TargetItemID, "level", item.Attributes.Level)
+	return nil
+}
+
+type assignWorkerToSquadRequest struct {
+	CharacterID string `json:"characterId"`
+	SquadID     string `json:"squadId" binding:"required"`
+	SlotIdx     int    `json:"slotIdx"`
+}
+
+// assignWorkerToSquad puts a character into a squad slot, unslotting whoever
+// held it. An empty characterId just clears the slot.
+func assignWorkerToSquad(ctx *MCPContext, req interface{}) error {
+	body := req.(*assignWorkerToSquadRequest)
+
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	squad, ok := data.Squads[body.SquadID]
+	if !ok {
+		return NewMCPError("errors.com.epicgames.fortnite.invalid_squad",
+			fmt.Sprintf("Squad '%s' not found", body.SquadID),
+			[]string{body.SquadID}, 16052, http.StatusBadRequest)
+	}
+	if body.SlotIdx < 0 || body.SlotIdx >= squad.Slots {
+		return NewMCPError("errors.com.epicgames.fortnite.invalid_squad_slot",
+			fmt.Sprintf("Squad '%s' has no slot %d", body.SquadID, body.SlotIdx),
+			[]string{body.SquadID, fmt.Sprint(body.SlotIdx)}, 16053, http.StatusBadRequest)
+	}
+
+	var character *models.ProfileItem
+	if body.CharacterID != "" {
+		character, ok = campaign.Items.Get(body.CharacterID)
+		if !ok {
+			return campaignItemNotFound(body.CharacterID)
+		}
+		if !containsFold(squad.ItemTypes, utils.TemplateType(character.TemplateID)) {
+			return NewMCPError("errors.com.epicgames.fortnite.invalid_squad_member",
+				fmt.Sprintf("Item '%s' can not be slotted into squad '%s'", body.CharacterID, body.SquadID),
+				[]string{body.CharacterID, body.SquadID}, 16054, http.StatusBadRequest)
+		}
+	}
+
+	for _, id := range campaign.Items.IDs() {
+		if id == body.CharacterID || !containsFold(squad.ItemTypes, utils.TemplateType(campaign.Items.TemplateID(id))) {
+			continue
+		}
+		member, ok := campaign.Items.Get(id)
+		if !ok {
+			continue
+		}
+		squadID, _ := member.Attributes.Extra("squad_id").(string)
+		slotIdx, ok := member.Attributes.Extra("squad_slot_idx").(float64)
+		if squadID != body.SquadID || !ok || int(slotIdx) != body.SlotIdx {
+			continue
+		}
+		member.Attributes.SetExtra("squad_id", "")
+		member.Attributes.SetExtra("squad_slot_idx", -1)
+		ctx.ItemAttrChanged("campaign", id, "squad_id", "")
+		ctx.ItemAttrChanged("campaign", id, "squad_slot_idx", -1)
+	}
+
+	if character != nil {
+		character.Attributes.SetExtra("squad_id", body.SquadID)
+		character.Attributes.SetExtra("squad_slot_idx", body.SlotIdx)
+		ctx.ItemAttrChanged("campaign", body.CharacterID, "squad_id", body.SquadID)
+		ctx.ItemAttrChanged("campaign", body.CharacterID, "squad_slot_idx", body.SlotIdx)
+	}
+	return nil
+}
+
+func containsFold(slice []string, item string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, item) {
+			return true
+		}
+	}
+	return false
+}
+
+type setHomebaseNameRequest struct {
+	HomebaseName string `json:"homebaseName" binding:"required"`
+}
+
+func setHomebaseName(ctx *MCPContext, req interface{}) error {
+	body := req.(*setHomebaseNameRequest)
+
+	data, err := utils.LoadCampaign()
+	if err != nil {
+		return NewMCPError("errors.com.epicgames.fortnite.campaign_unavailable",
+			"The campaign template is not available",
+			nil, 16099, http.StatusInternalServerError)
+	}
+	name := strings.TrimSpace(body.HomebaseName)
+	length := utf8.RuneCountInString(name)
+	if length < data.HomebaseName.MinLength || length > data.HomebaseName.MaxLength || !homebaseNamePattern.MatchString(name) {
+		return NewMCPError("errors.com.epicgames.fortnite.invalid_homebase_name",
+			fmt.Sprintf("Homebase names must be %d to %d letters, numbers or spaces",
+				data.HomebaseName.MinLength, data.HomebaseName.MaxLength),
+			[]string{name}, 16055, http.StatusBadRequest)
+	}
+
+	profile, err := ctx.Profile("common_public")
+	if err != nil {
+		return err
+	}
+	if profile.Stats.Attributes == nil {
+		profile.Stats.Attributes = map[string]interface{}{}
+	}
+	profile.Stats.Attributes["homebase_name"] = name
+	ctx.StatModified("common_public", "homebase_name", name)
+	return nil
+}
+
+// claimCollectionBookRewards grants the rewards of every collection book
+// level reached since the last claim.
+func claimCollectionBookRewards(ctx *MCPContext, req interface{}) error {
+	data, campaign, err := campaignContext(ctx)
+	if err != nil {
+		return err
+	}
+	collectionBook := &campaign.Stats.Attributes.CollectionBook
+	level := data.CollectionBook.BookLevel(collectionBook.BookXP)
+	claimed := collectionBook.MaxBookXPLevelAchieved
+	if level <= claimed {
+		return NewMCPError("errors.com.epicgames.fortnite.no_collection_book_rewards",
+			"No collection book rewards to claim",
+			nil, 16056, http.StatusBadRequest)
+	}
+
+	var rewards []utils.CampaignReward
+	for _, reward := range data.CollectionBook.Rewards {
+		if reward.Level > claimed && reward.Level <= level {
+			rewards = append(rewards, reward)
+		}
+	}
+	grantCampaignRewards(ctx, data, &campaign.Items, rewards)
+
+	collectionBook.MaxBookXPLevelAchieved = level
+	ctx.StatModified("campaign", "collection_book", collectionBook)
+	return nil
+}
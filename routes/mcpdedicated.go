@@ -0,0 +1,133 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/static/tokens"
+	"VentureBackend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "EndBattleRoyaleGame",
+		ProfileIDs: []string{"athena"},
+		Dedicated:  true,
+		Request:    func() interface{} { return &endBattleRoyaleGameRequest{} },
+		Handler:    endBattleRoyaleGame,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "UpdateQuestProgress",
+		ProfileIDs: []string{"athena"},
+		Dedicated:  true,
+		Request:    func() interface{} { return &updateQuestProgressRequest{} },
+		Handler:    updateQuestProgress,
+	})
+}
+
+// requireServerAccount rejects dedicated server operations from accounts
+// that aren't flagged as game servers. It must run after VerifyToken.
+func requireServerAccount(c *gin.Context) {
+	decoded := tokens.FromContext(c)
+	if decoded != nil {
+		if user, err := utils.FindUserByAccountID(decoded.AccountID); err == nil && user != nil && user.IsServer {
+			c.Next()
+			return
+		}
+	}
+	utils.CreateError(c,
+		"errors.com.epicgames.common.missing_permission",
+		"Sorry your login does not posses the permissions to perform dedicated server operations.",
+		nil, 1023, "Forbidden", http.StatusForbidden)
+	c.Abort()
+}
+
+type matchAccolade struct {
+	AccoladeID string `json:"accoladeId" binding:"required"`
+	XP         int    `json:"xp" binding:"min=0"`
+}
+
+type endBattleRoyaleGameRequest struct {
+	MatchID      string          `json:"matchId" binding:"required"`
+	Placement    int             `json:"placement" binding:"required,min=1"`
+	Eliminations int             `json:"eliminations" binding:"min=0"`
+	MatchXP      int             `json:"matchXp" binding:"min=0"`
+	Accolades    []matchAccolade `json:"accolades" binding:"dive"`
+	Progress     []QuestProgress `json:"progress" binding:"dive"`
+}
+
+// endBattleRoyaleGame applies a player's end of match results: lifetime
+// stats, match and accolade XP (with the season match boost), quest
+// progress and any level ups they lead to. Each match's results are applied
+// to a player once.
+func endBattleRoyaleGame(ctx *MCPContext, req interface{}) error {
+	body := req.(*endBattleRoyaleGameRequest)
+
+	reserved, err := utils.ReserveMatchResult(body.MatchID, ctx.AccountID)
+	if err != nil {
+		utils.Error.Logf("Failed to reserve match %s results of %s: %v", body.MatchID, ctx.AccountID, err)
+		return NewMCPError("errors.com.epicgames.fortnite.match_results_failed",
+			"Unable to apply the match results, try again later.",
+			[]string{body.MatchID}, 16062, http.StatusInternalServerError)
+	}
+	if !reserved {
+		return NewMCPError("errors.com.epicgames.fortnite.match_results_applied",
+			fmt.Sprintf("Results of match '%s' were already applied to %s", body.MatchID, ctx.AccountID),
+			[]string{body.MatchID, ctx.AccountID}, 16061, http.StatusConflict)
+	}
+	ctx.OnRollback(func() { utils.ReleaseMatchResult(body.MatchID, ctx.AccountID) })
+
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	attributes := &athena.Stats.Attributes
+	attributes.MatchesPlayed++
+	attributes.LifetimeEliminations += body.Eliminations
+	if body.Placement == 1 {
+		attributes.LifetimeWins++
+	}
+
+	earned := body.MatchXP
+	for _, accolade := range body.Accolades {
+		earned += accolade.XP
+	}
+	xp := int(float64(earned) * (1 + attributes.SeasonMatchBoost/100))
+
+	// Quests are optional for match results; without them only XP and
+	// stats are applied.
+	if quests, _ := utils.CurrentQuests(); quests != nil && len(body.Progress) > 0 {
+		_, questXP := advanceQuests(athena, quests, body.Progress)
+		xp += questXP
+	}
+	if limit := utils.MatchXPLimit(); limit > 0 && xp > limit {
+		xp = limit
+	}
+	return grantServerXP(ctx, athena, xp)
+}
+
+// grantServerXP adds XP a dedicated server awarded to athena and levels the
+// player up. Servers get athena back as a full profile update.
+func grantServerXP(ctx *MCPContext, athena *models.AthenaProfile, xp int) error {
+	athena.Stats.Attributes.XP += float64(xp)
+	ctx.MarkChanged("athena")
+	ctx.FullProfileUpdate("athena")
+	if xp == 0 {
+		return nil
+	}
+
+	if err := ctx.flush(); err != nil {
+		return err
+	}
+	utils.CheckAndLevelUp(ctx.Profiles)
+	// Level and tier rewards land in common_core and profile0.
+	for _, profileID := range []string{"common_core", "profile0"} {
+		if _, ok := ctx.Profiles.Profiles[profileID]; ok {
+			ctx.MarkChanged(profileID)
+			ctx.FullProfileUpdate(profileID)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,447 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"VentureBackend/ws/xmpp"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// giftBoxTemplates are the wraps a player can pick for a gift.
+var giftBoxTemplates = []string{"GiftBox:gb_default", "GiftBox:gb_giftwrap1", "GiftBox:gb_giftwrap2", "GiftBox:gb_giftwrap3"}
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "GiftCatalogEntry",
+		ProfileIDs: []string{"common_core"},
+		Request:    func() interface{} { return &giftCatalogEntryRequest{} },
+		Handler:    giftCatalogEntry,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "RemoveGiftBox",
+		ProfileIDs: []string{"athena", "common_core", "profile0"},
+		Request:    func() interface{} { return &removeGiftBoxRequest{} },
+		Handler:    removeGiftBox,
+	})
+}
+
+type giftCatalogEntryRequest struct {
+	OfferID            string   `json:"offerId" binding:"required"`
+	ReceiverAccountIDs []string `json:"receiverAccountIds" binding:"required"`
+	GiftWrapTemplateID string   `json:"giftWrapTemplateId" binding:"required"`
+	PersonalMessage    string   `json:"personalMessage"`
+}
+
+// giftCatalogEntry charges the sender for one copy of the offer per receiver
+// and, once that is saved, delivers each copy in a gift box. Receivers that
+// couldn't get their gift are refunded.
+func giftCatalogEntry(ctx *MCPContext, req interface{}) error {
+	body := req.(*giftCatalogEntryRequest)
+
+	if len(body.PersonalMessage) > 100 {
+		return NewMCPError("errors.com.epicgames.string.length_check",
+			"The personalMessage you provided is longer than 100 characters.",
+			nil, 16027, http.StatusBadRequest)
+	}
+	if !contains(giftBoxTemplates, body.GiftWrapTemplateID) {
+		return NewMCPError("errors.com.epicgames.giftbox.invalid",
+			"The giftbox you provided is invalid.",
+			nil, 16027, http.StatusBadRequest)
+	}
+	if len(body.ReceiverAccountIDs) < 1 || len(body.ReceiverAccountIDs) > 5 {
+		return NewMCPError("errors.com.epicgames.item.quantity.range_check",
+			"You need to at least gift to 1 person and can not gift to more than 5 people.",
+			nil, 16027, http.StatusBadRequest)
+	}
+	seen := map[string]bool{}
+	for _, receiverID := range body.ReceiverAccountIDs {
+		if seen[receiverID] {
+			return NewMCPError("errors.com.epicgames.array.duplicate_found",
+				"There are duplicate accountIds in receiverAccountIds.",
+				nil, 16027, http.StatusBadRequest)
+		}
+		seen[receiverID] = true
+	}
+
+	if err := checkGiftFriendships(ctx.AccountID, body.ReceiverAccountIDs); err != nil {
+		return err
+	}
+
+	_, entry := utils.GetOfferID(body.OfferID)
+	if entry == nil {
+		return NewMCPError("errors.com.epicgames.fortnite.id_invalid",
+			fmt.Sprintf("Offer ID (id: '%s') not found", body.OfferID),
+			[]string{body.OfferID}, 16027, http.StatusBadRequest)
+	}
+	// Gifts are paid in V-Bucks; offers sold for anything else can't be gifted.
+	currencyType, _, unitPrice := offerPrice(entry)
+	if !strings.EqualFold(currencyType, "MtxCurrency") {
+		return NewMCPError("errors.com.epicgames.modules.gamesubcatalog.offer_not_giftable",
+			fmt.Sprintf("Offer %s can not be gifted.", body.OfferID),
+			[]string{body.OfferID}, 28009, http.StatusForbidden)
+	}
+	currencyType = strings.ToLower(currencyType)
+
+	chargedItemID, err := chargeMtx(ctx, ctx.ProfileID, unitPrice*len(body.ReceiverAccountIDs))
+	if err != nil {
+		return err
+	}
+
+	// Every receiver has to be able to take the gift before anything is
+	// charged for it.
+	for _, receiverID := range body.ReceiverAccountIDs {
+		if err := checkGiftReceiver(receiverID); err != nil {
+			return err
+		}
+	}
+
+	slots, err := reserveGiftSlots(ctx.AccountID, body.ReceiverAccountIDs)
+	if err != nil {
+		return err
+	}
+	ctx.OnRollback(slots.release)
+
+	ctx.AfterCommit(func() error {
+		undelivered := []string{}
+		for _, receiverID := range body.ReceiverAccountIDs {
+			giftBoxID, granted, err := deliverGift(ctx.AccountID, receiverID, body, entry)
+			if err != nil {
+				utils.Error.Logf("Failed to deliver gift from %s to %s: %v", ctx.AccountID, receiverID, err)
+				undelivered = append(undelivered, receiverID)
+				continue
+			}
+			recordGift(ctx.AccountID, receiverID, body, currencyType, chargedItemID, unitPrice, giftBoxID, granted)
+		}
+		if len(undelivered) == 0 {
+			return nil
+		}
+
+		slots.releaseUndelivered(undelivered)
+		if refunded := refundUndeliveredGifts(ctx.AccountID, body.OfferID, currencyType, chargedItemID, unitPrice, undelivered); refunded != nil {
+			ctx.RefreshProfiles(refunded, ctx.ProfileID)
+		}
+		if len(undelivered) == len(body.ReceiverAccountIDs) {
+			return NewMCPError("errors.com.epicgames.modules.gamesubcatalog.gift_failed",
+				fmt.Sprintf("The gift could not be delivered to %s.", strings.Join(undelivered, ", ")),
+				undelivered, 28010, http.StatusInternalServerError)
+		}
+		return nil
+	})
+	return nil
+}
+
+// checkGiftFriendships requires the sender to have been friends with every
+// other receiver for the configured minimum time.
+func checkGiftFriendships(accountID string, receiverIDs []string) error {
+	var friends models.Friends
+	_ = utils.FriendsCollection.FindOne(context.TODO(), bson.M{"accountId": accountID}).Decode(&friends)
+	accepted := map[string]models.FriendEntry{}
+	for _, f := range friends.List.Accepted {
+		accepted[f.AccountID] = f
+	}
+
+	minFriendshipAge := utils.GiftMinFriendshipAge()
+	for _, receiverID := range receiverIDs {
+		if receiverID == accountID {
+			continue
+		}
+		friend, ok := accepted[receiverID]
+		if !ok {
+			return NewMCPError("errors.com.epicgames.friends.no_relationship",
+				fmt.Sprintf("User %s is not friends with %s", accountID, receiverID),
+				[]string{accountID, receiverID}, 28004, http.StatusForbidden)
+		}
+		since, err := time.Parse(time.RFC3339, friend.Created)
+		if err != nil || time.Since(since) < minFriendshipAge {
+			return NewMCPError("errors.com.epicgames.friends.friendship_too_new",
+				fmt.Sprintf("You need to be friends with %s for at least %s before sending them a gift.", receiverID, minFriendshipAge),
+				[]string{receiverID, minFriendshipAge.String()}, 28005, http.StatusForbidden)
+		}
+	}
+	return nil
+}
+
+// checkGiftReceiver requires the receiver to exist and accept gifts.
+func checkGiftReceiver(receiverID string) error {
+	receiverProfiles, err := utils.FindProfileByAccountID(receiverID)
+	if err != nil || receiverProfiles == nil {
+		return NewMCPError("errors.com.epicgames.modules.userProfiles.not_found",
+			fmt.Sprintf("Profile of user %s not found.", receiverID),
+			[]string{receiverID}, 12804, http.StatusNotFound)
+	}
+	var commonCore models.CommonCoreProfile
+	if err := models.DecodeProfile(receiverProfiles.Profiles["common_core"], &commonCore); err != nil ||
+		!commonCore.Stats.Attributes.AllowedToReceiveGifts {
+		return NewMCPError("errors.com.epicgames.user.gift_disabled",
+			fmt.Sprintf("User %s has disabled receiving gifts.", receiverID),
+			[]string{receiverID}, 28004, http.StatusForbidden)
+	}
+	return nil
+}
+
+// giftSlots are the daily gift caps one GiftCatalogEntry reserved.
+type giftSlots struct {
+	sender    *utils.GiftSlot
+	receivers map[string]*utils.GiftSlot
+}
+
+// reserveGiftSlots takes the gifts from the sender's and receivers' daily
+// caps before the sender is charged, so parallel gifts can't both fit into the
+// last slot.
+func reserveGiftSlots(senderID string, receiverIDs []string) (*giftSlots, error) {
+	slots := &giftSlots{receivers: map[string]*utils.GiftSlot{}}
+	if limit := utils.GiftDailySendLimit(); limit > 0 {
+		slot, ok, err := utils.ReserveGiftSlots("senderId", senderID, len(receiverIDs), limit)
+		if err != nil {
+			utils.Error.Logf("Failed to reserve gifts sent by %s: %v", senderID, err)
+		}
+		if !ok {
+			return nil, NewMCPError("errors.com.epicgames.modules.gamesubcatalog.gift_limit_reached",
+				fmt.Sprintf("You can only send %d gifts per day.", limit),
+				[]string{strconv.Itoa(limit)}, 28006, http.StatusForbidden)
+		}
+		slots.sender = slot
+	}
+	if limit := utils.GiftDailyReceiveLimit(); limit > 0 {
+		for _, receiverID := range receiverIDs {
+			slot, ok, err := utils.ReserveGiftSlots("receiverId", receiverID, 1, limit)
+			if err != nil {
+				utils.Error.Logf("Failed to reserve gifts received by %s: %v", receiverID, err)
+			}
+			if !ok {
+				slots.release()
+				return nil, NewMCPError("errors.com.epicgames.modules.gamesubcatalog.gift_recipient_limit_reached",
+					fmt.Sprintf("User %s can not receive any more gifts today.", receiverID),
+					[]string{receiverID}, 28007, http.StatusForbidden)
+			}
+			slots.receivers[receiverID] = slot
+		}
+	}
+	return slots, nil
+}
+
+func (s *giftSlots) release() {
+	all := []*utils.GiftSlot{s.sender}
+	for _, slot := range s.receivers {
+		all = append(all, slot)
+	}
+	utils.ReleaseGiftSlots(all...)
+}
+
+// releaseUndelivered hands back the slots of gifts that didn't go out.
+func (s *giftSlots) releaseUndelivered(receiverIDs []string) {
+	unused := []*utils.GiftSlot{}
+	if s.sender != nil {
+		unused = append(unused, &utils.GiftSlot{Field: s.sender.Field, AccountID: s.sender.AccountID, Day: s.sender.Day, Count: len(receiverIDs)})
+	}
+	for _, receiverID := range receiverIDs {
+		unused = append(unused, s.receivers[receiverID])
+	}
+	utils.ReleaseGiftSlots(unused...)
+}
+
+// deliverGift puts the offer's items into the receiver's athena and a gift box
+// listing them into their common_core.
+func deliverGift(senderID, receiverID string, body *giftCatalogEntryRequest, entry *utils.CatalogEntry) (string, []models.GiftedItem, error) {
+	var giftBoxID string
+	var granted []models.GiftedItem
+	_, err := utils.UpdateProfiles(receiverID, func(doc *models.Profiles) ([]string, error) {
+		ctx := newMCPContext(nil, receiverID, "common_core")
+		ctx.reset(doc)
+		athena, err := ctx.Athena()
+		if err != nil {
+			return nil, err
+		}
+		commonCore, err := ctx.CommonCore()
+		if err != nil {
+			return nil, err
+		}
+
+		granted = nil
+		giftBox := models.NewProfileItem(body.GiftWrapTemplateID)
+		giftBox.Attributes.FromAccountID = senderID
+		giftBox.Attributes.LootList = []models.LootResult{}
+		giftBox.Attributes.SetExtra("params", map[string]interface{}{"userMessage": body.PersonalMessage})
+		giftBox.Attributes.Level = 1
+		giftBox.Attributes.GiftedOn = time.Now().UTC().Format(time.RFC3339)
+
+		for _, grant := range entry.ItemGrants {
+			templateID, _ := grant["templateId"].(string)
+			itemID := utils.GenerateRandomID()
+			item := models.NewProfileItem(templateID)
+			item.Attributes.Variants = []models.ItemVariant{}
+			athena.Items.Set(itemID, item)
+			ctx.ItemAdded("athena", itemID, item)
+			granted = append(granted, models.GiftedItem{ItemID: itemID, TemplateID: templateID, ProfileID: "athena"})
+
+			giftBox.Attributes.LootList = append(giftBox.Attributes.LootList, models.LootResult{
+				ItemType:    templateID,
+				ItemGuid:    itemID,
+				ItemProfile: "athena",
+				Quantity:    1,
+			})
+		}
+
+		giftBoxID = utils.GenerateRandomID()
+		commonCore.Items.Set(giftBoxID, giftBox)
+		ctx.ItemAdded("common_core", giftBoxID, giftBox)
+		return ctx.commit()
+	})
+	return giftBoxID, granted, err
+}
+
+// recordGift keeps a delivered gift for admin reversal, writes both sides to
+// the ledger and tells the receiver's client.
+func recordGift(senderID, receiverID string, body *giftCatalogEntryRequest, currencyType, chargedItemID string, unitPrice int, giftBoxID string, granted []models.GiftedItem) {
+	if err := utils.RecordGift(models.Gift{
+		SenderID:      senderID,
+		ReceiverID:    receiverID,
+		OfferID:       body.OfferID,
+		Price:         unitPrice,
+		CurrencyType:  currencyType,
+		ChargedItemID: chargedItemID,
+		GiftBoxID:     giftBoxID,
+		GrantedItems:  granted,
+		Message:       body.PersonalMessage,
+	}); err != nil {
+		utils.Error.Logf("Failed to record gift from %s to %s: %v", senderID, receiverID, err)
+	}
+
+	receivedItems := []models.ReceiptItem{}
+	for _, item := range granted {
+		receivedItems = append(receivedItems, models.ReceiptItem{TemplateID: item.TemplateID, ItemID: item.ItemID, Quantity: 1})
+	}
+	sentReceipt := models.Receipt{
+		ReceiptID:        utils.GenerateRandomID(),
+		AccountID:        senderID,
+		Type:             models.ReceiptGiftSent,
+		OfferID:          body.OfferID,
+		CurrencyType:     currencyType,
+		Amount:           -unitPrice,
+		RelatedAccountID: receiverID,
+	}
+	utils.RecordReceipt(sentReceipt)
+	utils.RecordReceipt(models.Receipt{
+		AccountID:        receiverID,
+		Type:             models.ReceiptGiftReceived,
+		OfferID:          body.OfferID,
+		Items:            receivedItems,
+		RelatedAccountID: senderID,
+		RelatedReceiptID: sentReceipt.ReceiptID,
+	})
+
+	xmpp.SendXmppMessageToId(map[string]interface{}{
+		"type":      "com.epicgames.gift.received",
+		"payload":   map[string]interface{}{},
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}, receiverID)
+}
+
+// refundUndeliveredGifts gives the sender back what they were charged for
+// receivers whose gift could not be delivered and writes the charge and its
+// refund to the ledger. It returns the sender's refreshed profiles, or nil if
+// nothing was refunded.
+func refundUndeliveredGifts(accountID, offerID, currencyType, chargedItemID string, unitPrice int, receiverIDs []string) *models.Profiles {
+	amount := unitPrice * len(receiverIDs)
+	var doc *models.Profiles
+	var err error
+	if amount > 0 {
+		doc, err = utils.UpdateProfiles(accountID, func(doc *models.Profiles) ([]string, error) {
+			ctx := newMCPContext(nil, accountID, "common_core")
+			ctx.reset(doc)
+			commonCore, err := ctx.CommonCore()
+			if err != nil {
+				return nil, err
+			}
+			currency, ok := commonCore.Items.Get(chargedItemID)
+			if !ok {
+				return nil, fmt.Errorf("currency item %s missing", chargedItemID)
+			}
+			currency.Quantity += amount
+			ctx.ItemQuantityChanged("common_core", chargedItemID, currency.Quantity)
+			return ctx.commit()
+		})
+		if err != nil {
+			utils.Error.Logf("Failed to refund %d %s to %s for undelivered gifts of %s: %v", amount, currencyType, accountID, offerID, err)
+		}
+	}
+
+	for _, receiverID := range receiverIDs {
+		charge := models.Receipt{
+			ReceiptID:        utils.GenerateRandomID(),
+			AccountID:        accountID,
+			Type:             models.ReceiptGiftSent,
+			OfferID:          offerID,
+			CurrencyType:     currencyType,
+			Amount:           -unitPrice,
+			RelatedAccountID: receiverID,
+			Note:             "Gift could not be delivered",
+		}
+		utils.RecordReceipt(charge)
+		if err != nil || unitPrice == 0 {
+			continue
+		}
+		utils.RecordReceipt(models.Receipt{
+			AccountID:        accountID,
+			Type:             models.ReceiptRefund,
+			OfferID:          offerID,
+			CurrencyType:     currencyType,
+			Amount:           unitPrice,
+			RelatedAccountID: receiverID,
+			RelatedReceiptID: charge.ReceiptID,
+			Note:             "Refund for undelivered gift",
+		})
+	}
+
+	if err != nil {
+		return nil
+	}
+	return doc
+}
+
+type removeGiftBoxRequest struct {
+	GiftBoxItemID  string   `json:"giftBoxItemId"`
+	GiftBoxItemIDs []string `json:"giftBoxItemIds"`
+}
+
+// removeGiftBox deletes opened gift boxes. A single giftBoxItemId must be a
+// gift box; the batch form skips ids that aren't.
+func removeGiftBox(ctx *MCPContext, req interface{}) error {
+	body := req.(*removeGiftBoxRequest)
+
+	items, err := ctx.Items(ctx.ProfileID)
+	if err != nil {
+		return err
+	}
+
+	if body.GiftBoxItemID != "" {
+		if !items.Has(body.GiftBoxItemID) {
+			return NewMCPError("errors.com.epicgames.fortnite.id_invalid",
+				fmt.Sprintf("Item (id: '%s') not found", body.GiftBoxItemID),
+				[]string{body.GiftBoxItemID}, 16027, http.StatusBadRequest)
+		}
+		if !strings.HasPrefix(items.TemplateID(body.GiftBoxItemID), "GiftBox:") {
+			return NewMCPError("errors.com.epicgames.fortnite.id_invalid",
+				"The specified item id is not a giftbox.",
+				[]string{body.GiftBoxItemID}, 16027, http.StatusBadRequest)
+		}
+		items.Delete(body.GiftBoxItemID)
+		ctx.ItemRemoved(ctx.ProfileID, body.GiftBoxItemID)
+	}
+
+	for _, giftBoxItemID := range body.GiftBoxItemIDs {
+		if !items.Has(giftBoxItemID) || !strings.HasPrefix(items.TemplateID(giftBoxItemID), "GiftBox:") {
+			continue
+		}
+		items.Delete(giftBoxItemID)
+		ctx.ItemRemoved(ctx.ProfileID, giftBoxItemID)
+	}
+	return nil
+}
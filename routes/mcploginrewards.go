@@ -0,0 +1,78 @@
+package routes
+
+import (
+	"VentureBackend/utils"
+	"net/http"
+	"time"
+)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "ClaimLoginReward",
+		ProfileIDs: []string{"common_core"},
+		Handler:    claimLoginReward,
+	})
+}
+
+// claimLoginReward grants the next day of the login reward calendar once per
+// UTC day, wrapped in a gift box on common_core.
+func claimLoginReward(ctx *MCPContext, req interface{}) error {
+	calendar, err := utils.LoadLoginRewards()
+	if err != nil || calendar.Days() == 0 {
+		return NewMCPError("errors.com.epicgames.fortnite.login_rewards_unavailable",
+			"No login rewards are configured",
+			nil, 16099, http.StatusBadRequest)
+	}
+
+	commonCore, err := ctx.CommonCore()
+	if err != nil {
+		return err
+	}
+	dailyRewards := &commonCore.Stats.Attributes.DailyRewards
+
+	now := time.Now().UTC()
+	lastClaim, _ := time.Parse(time.RFC3339, dailyRewards.LastClaimDate)
+	if lastClaim.Truncate(24 * time.Hour).Equal(now.Truncate(24 * time.Hour)) {
+		return NewMCPError("errors.com.epicgames.fortnite.login_reward_claimed",
+			"Today's login reward was already claimed",
+			nil, 16042, http.StatusBadRequest)
+	}
+
+	day := dailyRewards.TotalDaysLoggedIn%calendar.Days() + 1
+	dailyRewards.TotalDaysLoggedIn++
+	dailyRewards.NextDefaultReward = day%calendar.Days() + 1
+	dailyRewards.LastClaimDate = now.Format(time.RFC3339)
+	ctx.StatModified("common_core", "daily_rewards", dailyRewards)
+
+	rewards := calendar.RewardsForDay(day)
+	if len(rewards) == 0 {
+		return nil
+	}
+	// Rewards are granted on the stored profiles, which need the claim
+	// written back first.
+	if err := ctx.flush(); err != nil {
+		return err
+	}
+	giftBoxID := utils.GrantRewards(ctx.Profiles, rewards, calendar.GiftBoxTemplateID)
+	if giftBoxID == "" {
+		return NewMCPError("errors.com.epicgames.fortnite.login_reward_failed",
+			"Failed to grant the login reward",
+			nil, 16043, http.StatusInternalServerError)
+	}
+	// XP rewards may level the player up.
+	utils.CheckAndLevelUp(ctx.Profiles)
+
+	items, err := ctx.Items("common_core")
+	if err != nil {
+		return err
+	}
+	if giftBox, ok := items.Get(giftBoxID); ok {
+		ctx.ItemAdded("common_core", giftBoxID, giftBox)
+	}
+	ctx.MarkChanged("athena")
+	ctx.FullProfileUpdate("athena")
+	if _, ok := ctx.Profiles.Profiles["profile0"]; ok {
+		ctx.MarkChanged("profile0")
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+func init() {
+	// Commands the client sends that only need the profile back.
+	for _, name := range []string{
+		"QueryProfile", "RefreshExpeditions", "GetMcpTimeForLogin",
+		"IncrementNamedCounterStat", "SetHardcoreModifier", "SetMtxPlatform", "BulkEquipBattleRoyaleCustomization",
+	} {
+		RegisterMCPOperation(MCPOperation{Name: name})
+	}
+
+	RegisterMCPOperation(MCPOperation{
+		Name:    "MarkItemSeen",
+		Request: func() interface{} { return &markItemSeenRequest{} },
+		Handler: markItemSeen,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "SetItemFavoriteStatusBatch",
+		ProfileIDs: []string{"athena"},
+		Request:    func() interface{} { return &setItemFavoriteStatusBatchRequest{} },
+		Handler:    setItemFavoriteStatusBatch,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "SetBattleRoyaleBanner",
+		ProfileIDs: []string{"athena"},
+		Request:    func() interface{} { return &setBattleRoyaleBannerRequest{} },
+		Handler:    setBattleRoyaleBanner,
+	})
+}
+
+type markItemSeenRequest struct {
+	ItemIds []string `json:"itemIds" binding:"required"`
+}
+
+func markItemSeen(ctx *MCPContext, req interface{}) error {
+	body := req.(*markItemSeenRequest)
+
+	items, err := ctx.Items(ctx.ProfileID)
+	if err != nil {
+		return err
+	}
+	for _, id := range body.ItemIds {
+		item, exists := items.Get(id)
+		if !exists {
+			continue
+		}
+		item.Attributes.ItemSeen = true
+		ctx.ItemAttrChanged(ctx.ProfileID, id, "item_seen", true)
+	}
+	return nil
+}
+
+type setItemFavoriteStatusBatchRequest struct {
+	ItemIds       []string `json:"itemIds" binding:"required"`
+	ItemFavStatus []bool   `json:"itemFavStatus" binding:"required"`
+}
+
+func setItemFavoriteStatusBatch(ctx *MCPContext, req interface{}) error {
+	body := req.(*setItemFavoriteStatusBatchRequest)
+
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < len(body.ItemIds) && i < len(body.ItemFavStatus); i++ {
+		id := body.ItemIds[i]
+		item, exists := athena.Items.Get(id)
+		if !exists {
+			continue
+		}
+		item.Attributes.Favorite = body.ItemFavStatus[i]
+		ctx.ItemAttrChanged("athena", id, "favorite", body.ItemFavStatus[i])
+	}
+	return nil
+}
+
+type setBattleRoyaleBannerRequest struct {
+	HomebaseBannerIconId  string `json:"homebaseBannerIconId" binding:"required"`
+	HomebaseBannerColorId string `json:"homebaseBannerColorId" binding:"required"`
+}
+
+func setBattleRoyaleBanner(ctx *MCPContext, req interface{}) error {
+	body := req.(*setBattleRoyaleBannerRequest)
+
+	bannerProfileId := "common_core"
+	if ctx.Version.Build < 3.5 {
+		bannerProfileId = "profile0"
+	}
+	bannerItems, err := ctx.Items(bannerProfileId)
+	if err != nil {
+		return err
+	}
+
+	iconTemplate := "HomebaseBannerIcon:" + body.HomebaseBannerIconId
+	colorTemplate := "HomebaseBannerColor:" + body.HomebaseBannerColorId
+	if !ownsTemplate(bannerItems, iconTemplate) {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_found",
+			fmt.Sprintf("Banner template '%s' not found in profile", iconTemplate),
+			[]string{iconTemplate}, 16006, http.StatusBadRequest)
+	}
+	if !ownsTemplate(bannerItems, colorTemplate) {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_found",
+			fmt.Sprintf("Banner template '%s' not found in profile", colorTemplate),
+			[]string{colorTemplate}, 16006, http.StatusBadRequest)
+	}
+
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	attributes := &athena.Stats.Attributes
+	index := attributes.ActiveLoadoutIndex
+	if index < 0 || index >= len(attributes.Loadouts) {
+		return NewMCPError("errors.com.epicgames.modules.profiles.invalid_data",
+			"Active loadouts missing or invalid",
+			nil, 12814, http.StatusInternalServerError)
+	}
+	loadout, ok := athena.Items.Get(attributes.Loadouts[index])
+	if !ok {
+		return NewMCPError("errors.com.epicgames.modules.profiles.invalid_data",
+			"Active loadout item not found",
+			nil, 12814, http.StatusInternalServerError)
+	}
+
+	attributes.BannerIcon = body.HomebaseBannerIconId
+	attributes.BannerColor = body.HomebaseBannerColorId
+	loadout.Attributes.BannerIcon = body.HomebaseBannerIconId
+	loadout.Attributes.BannerColor = body.HomebaseBannerColorId
+
+	ctx.StatModified("athena", "banner_icon", attributes.BannerIcon)
+	ctx.StatModified("athena", "banner_color", attributes.BannerColor)
+	return nil
+}
+
+func ownsTemplate(items *models.ProfileItems, templateId string) bool {
+	for _, id := range items.IDs() {
+		if strings.EqualFold(items.TemplateID(id), templateId) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"net/http"
+)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "VerifyRealMoneyPurchase",
+		ProfileIDs: []string{"common_core"},
+		Request:    func() interface{} { return &verifyRealMoneyPurchaseRequest{} },
+		Handler:    verifyRealMoneyPurchase,
+	})
+}
+
+// verifyRealMoneyPurchaseRequest names the order PurchaseCatalogEntry
+// placed in ReceiptID.
+type verifyRealMoneyPurchaseRequest struct {
+	AppStore              string `json:"appStore"`
+	AppStoreID            string `json:"appStoreId"`
+	ReceiptID             string `json:"receiptId" binding:"required"`
+	ReceiptInfo           string `json:"receiptInfo"`
+	PurchaseCorrelationID string `json:"purchaseCorrelationId"`
+}
+
+// verifyRealMoneyPurchase tells the client whether a real-money order went
+// through. The provider's callback credits the V-Bucks; a completed order
+// that wasn't credited yet is credited again before the client retries.
+func verifyRealMoneyPurchase(ctx *MCPContext, req interface{}) error {
+	body := req.(*verifyRealMoneyPurchaseRequest)
+
+	payment, err := utils.FindPayment(body.ReceiptID)
+	if err != nil {
+		utils.Error.Logf("Failed to read order %s: %v", body.ReceiptID, err)
+		return NewMCPError("errors.com.epicgames.modules.payments.order_failed",
+			"Unable to read the order, try again later.",
+			[]string{body.ReceiptID}, 16057, http.StatusInternalServerError)
+	}
+	if payment == nil || payment.AccountID != ctx.AccountID {
+		return NewMCPError("errors.com.epicgames.modules.payments.order_not_found",
+			"Order "+body.ReceiptID+" not found",
+			[]string{body.ReceiptID}, 16058, http.StatusNotFound)
+	}
+
+	switch payment.Status {
+	case models.PaymentPending:
+		return NewMCPError("errors.com.epicgames.modules.payments.order_pending",
+			"The payment for order "+payment.OrderID+" has not completed yet",
+			[]string{payment.OrderID}, 16059, http.StatusConflict)
+	case models.PaymentFailed:
+		return NewMCPError("errors.com.epicgames.modules.payments.order_failed",
+			"The payment for order "+payment.OrderID+" failed",
+			[]string{payment.OrderID}, 16060, http.StatusBadRequest)
+	}
+
+	// The credit was saved outside this command, so the client's revision
+	// is behind and the response carries the whole profile.
+	commonCore, _ := ctx.Profiles.Profiles["common_core"].(map[string]interface{})
+	if !utils.HasRealMoneyPurchase(commonCore, payment.OrderID) {
+		if _, err := utils.FulfillPayment(payment); err != nil {
+			utils.Error.Logf("Failed to fulfill order %s: %v", payment.OrderID, err)
+		}
+		return NewMCPError("errors.com.epicgames.modules.payments.order_pending",
+			"Order "+payment.OrderID+" is still being delivered, try again",
+			[]string{payment.OrderID}, 16059, http.StatusConflict)
+	}
+	return nil
+}
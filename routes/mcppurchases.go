@@ -0,0 +1,697 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// storefrontPattern matches the item shop storefronts PurchaseCatalogEntry
+// grants cosmetics from.
+var storefrontPattern = regexp.MustCompile(`^BR(Daily|Weekly|Season)Storefront$`)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:       "PurchaseCatalogEntry",
+		ProfileIDs: []string{"common_core", "profile0"},
+		Request:    func() interface{} { return &purchaseCatalogEntryRequest{} },
+		Handler:    purchaseCatalogEntry,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "RefundMtxPurchase",
+		ProfileIDs: []string{"common_core", "profile0"},
+		Request:    func() interface{} { return &refundMtxPurchaseRequest{} },
+		Handler:    refundMtxPurchase,
+	})
+}
+
+// offerPrice reads the first price of a catalog entry.
+func offerPrice(entry *utils.CatalogEntry) (currencyType, currencySubType string, finalPrice int) {
+	if len(entry.Prices) == 0 {
+		return "", "", 0
+	}
+	price := entry.Prices[0]
+	currencyType, _ = price["currencyType"].(string)
+	currencySubType, _ = price["currencySubType"].(string)
+	switch v := price["finalPrice"].(type) {
+	case int:
+		finalPrice = v
+	case int32:
+		finalPrice = int(v)
+	case int64:
+		finalPrice = int(v)
+	case float64:
+		finalPrice = int(v)
+	}
+	return currencyType, currencySubType, finalPrice
+}
+
+// mtxCurrency finds the V-Bucks the wallet spends: those of its current
+// platform, or shared ones.
+func mtxCurrency(wallet *models.CommonCoreProfile) (string, *models.ProfileItem) {
+	platform := strings.ToLower(wallet.Stats.Attributes.CurrentMtxPlatform)
+	for _, id := range wallet.Items.IDs() {
+		if !strings.HasPrefix(strings.ToLower(wallet.Items.TemplateID(id)), "currency:mtx") {
+			continue
+		}
+		item, ok := wallet.Items.Get(id)
+		if !ok {
+			continue
+		}
+		itemPlatform := strings.ToLower(item.Attributes.Platform)
+		if itemPlatform == platform || itemPlatform == "shared" {
+			return id, item
+		}
+	}
+	return "", nil
+}
+
+// chargeMtx takes price V-Bucks from the wallet profile and returns the
+// currency item they came from.
+func chargeMtx(ctx *MCPContext, profileID string, price int) (string, error) {
+	if price <= 0 {
+		return "", nil
+	}
+	wallet, err := ctx.Wallet(profileID)
+	if err != nil {
+		return "", err
+	}
+	currencyID, currency := mtxCurrency(wallet)
+	if currency == nil {
+		return "", NewMCPError("errors.com.epicgames.currency.mtx.insufficient",
+			"You can not afford this item.",
+			nil, 1040, http.StatusBadRequest)
+	}
+	if currency.Quantity < price {
+		return "", NewMCPError("errors.com.epicgames.currency.mtx.insufficient",
+			fmt.Sprintf("You can not afford this item (%d), you only have %d.", price, currency.Quantity),
+			[]string{strconv.Itoa(price), strconv.Itoa(currency.Quantity)}, 1040, http.StatusBadRequest)
+	}
+	currency.Quantity -= price
+	ctx.ItemQuantityChanged(profileID, currencyID, currency.Quantity)
+	return currencyID, nil
+}
+
+// creditMtx adds amount V-Bucks to the wallet profile. It reports false when
+// the wallet has no V-Bucks for its platform to add them to.
+func creditMtx(ctx *MCPContext, profileID string, amount int) (bool, error) {
+	wallet, err := ctx.Wallet(profileID)
+	if err != nil {
+		return false, err
+	}
+	currencyID, currency := mtxCurrency(wallet)
+	if currency == nil {
+		return false, nil
+	}
+	currency.Quantity += amount
+	ctx.ItemQuantityChanged(profileID, currencyID, currency.Quantity)
+	return true, nil
+}
+
+// ownedTemplates lists the lower-cased templates of the items in a profile.
+func ownedTemplates(items *models.ProfileItems) map[string]bool {
+	owned := map[string]bool{}
+	for _, id := range items.IDs() {
+		owned[strings.ToLower(items.TemplateID(id))] = true
+	}
+	return owned
+}
+
+type purchaseCatalogEntryRequest struct {
+	OfferID          string `json:"offerId" binding:"required"`
+	PurchaseQuantity int    `json:"purchaseQuantity"`
+}
+
+// purchaseCatalogEntry buys an offer: real-money offers place an order, the
+// battle pass and its tiers level the pass, and storefront offers grant
+// their cosmetics. The purchase counts against the offer's limits from the
+// moment it is checked; the reservation is handed back if nothing is bought.
+func purchaseCatalogEntry(ctx *MCPContext, req interface{}) error {
+	body := req.(*purchaseCatalogEntryRequest)
+	if body.PurchaseQuantity < 1 {
+		return NewMCPError("errors.com.epicgames.validation.validation_failed",
+			"Validation Failed. 'purchaseQuantity' is less than 1.",
+			[]string{"purchaseQuantity"}, 1040, http.StatusBadRequest)
+	}
+
+	storefront, entry := utils.GetOfferID(body.OfferID)
+	if entry == nil {
+		return NewMCPError("errors.com.epicgames.fortnite.id_invalid",
+			fmt.Sprintf("Offer ID (id: '%s') not found", body.OfferID),
+			[]string{body.OfferID}, 16027, http.StatusBadRequest)
+	}
+	// Item shop offers grant one copy, so they count once against the limit.
+	if storefrontPattern.MatchString(storefront) && body.PurchaseQuantity != 1 {
+		return NewMCPError("errors.com.epicgames.validation.validation_failed",
+			"Validation Failed. 'purchaseQuantity' must be 1 for item shop offers.",
+			[]string{"purchaseQuantity"}, 1040, http.StatusBadRequest)
+	}
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+
+	limitSlot, err := reservePurchaseLimit(ctx.AccountID, entry, body.PurchaseQuantity)
+	if err != nil {
+		return err
+	}
+	ctx.OnRollback(func() { utils.ReleasePurchases(limitSlot) })
+	// Charge the bundle price the catalog showed this account.
+	utils.PriceBundle(entry, ownedTemplates(&athena.Items))
+
+	bought := false
+	// RealMoney offers are paid outside the game. They place an order that
+	// the payment provider's callback settles.
+	if strings.EqualFold(entry.OfferType, "RealMoney") {
+		bought = true
+		ctx.AfterCommit(func() error {
+			payment, err := utils.CreatePayment(ctx.AccountID, entry, body.PurchaseQuantity)
+			if err != nil {
+				utils.Error.Logf("Failed to place order for %s by %s: %v", entry.OfferID, ctx.AccountID, err)
+				utils.ReleasePurchases(limitSlot)
+				return NewMCPError("errors.com.epicgames.modules.payments.order_failed",
+					"Unable to place the order, try again later.",
+					[]string{entry.OfferID}, 16057, http.StatusInternalServerError)
+			}
+			ctx.Notify(gin.H{
+				"type":       "RealMoneyPurchasePending",
+				"primary":    true,
+				"orderId":    payment.OrderID,
+				"offerId":    payment.OfferID,
+				"appStoreId": payment.AppStoreID,
+			})
+			return nil
+		})
+	}
+
+	var receipt *models.Receipt
+	if battlePass := currentBattlePass(ctx.Version); battlePass != nil && battlePass.sells(body.OfferID) {
+		receipt, err = purchaseBattlePass(ctx, athena, battlePass, entry, body.PurchaseQuantity)
+		if err != nil {
+			return err
+		}
+	}
+	if storefrontPattern.MatchString(storefront) {
+		receipt, err = purchaseStorefrontEntry(ctx, athena, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	if receipt != nil {
+		bought = true
+		receipt.AccountID = ctx.AccountID
+		ctx.AfterCommit(func() error {
+			utils.RecordReceipt(*receipt)
+			return nil
+		})
+	}
+	if !bought {
+		utils.ReleasePurchases(limitSlot)
+	}
+	return nil
+}
+
+// reservePurchaseLimit takes the purchase from the offer's daily, weekly and
+// monthly limits, refusing it once one is used up. Failures refuse limited
+// offers too.
+func reservePurchaseLimit(accountID string, entry *utils.CatalogEntry, quantity int) (*utils.PurchaseLimitSlot, error) {
+	slot, ok, err := utils.ReservePurchases(accountID, entry, quantity, time.Now())
+	if err != nil {
+		utils.Error.Logf("Failed to reserve purchases of %s by %s: %v", entry.OfferID, accountID, err)
+	}
+	if ok {
+		return slot, nil
+	}
+
+	counts, _ := utils.CountOfferPurchases(accountID, time.Now())
+	remaining := max(utils.RemainingPurchases(entry, counts[entry.OfferID]), 0)
+	return nil, NewMCPError("errors.com.epicgames.modules.gamesubcatalog.purchase_limit_reached",
+		fmt.Sprintf("Offer %s can only be purchased %d more time(s) in this period.", entry.OfferID, remaining),
+		[]string{entry.OfferID, strconv.Itoa(remaining)}, 28008, http.StatusForbidden)
+}
+
+// battlePassData is static/responses/BattlePass/Season<N>.json: the season's
+// offers and the rewards of every tier.
+type battlePassData struct {
+	BattlePassOfferID   string           `json:"battlePassOfferId"`
+	BattleBundleOfferID string           `json:"battleBundleOfferId"`
+	TierOfferID         string           `json:"tierOfferId"`
+	FreeRewards         []map[string]int `json:"freeRewards"`
+	PaidRewards         []map[string]int `json:"paidRewards"`
+}
+
+// currentBattlePass loads the battle pass of the configured season, or nil
+// when the client plays another season or none is configured.
+func currentBattlePass(version utils.VersionInfo) *battlePassData {
+	season := os.Getenv("SEASON")
+	if fmt.Sprint(version.Season) != season {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(".", "static", "responses", "BattlePass", "Season"+season+".json"))
+	if err != nil {
+		return nil
+	}
+	var battlePass battlePassData
+	if err := json.Unmarshal(data, &battlePass); err != nil {
+		return nil
+	}
+	return &battlePass
+}
+
+func (bp *battlePassData) sells(offerID string) bool {
+	return offerID == bp.BattlePassOfferID || offerID == bp.BattleBundleOfferID || offerID == bp.TierOfferID
+}
+
+// purchaseBattlePass charges for the pass, the bundle or quantity tiers and
+// grants the rewards of the tiers it unlocks in a gift box.
+func purchaseBattlePass(ctx *MCPContext, athena *models.AthenaProfile, bp *battlePassData, entry *utils.CatalogEntry, quantity int) (*models.Receipt, error) {
+	currencyType, _, unitPrice := offerPrice(entry)
+	price := unitPrice * quantity
+	mtx := strings.EqualFold(currencyType, "MtxCurrency")
+	if mtx {
+		if _, err := chargeMtx(ctx, ctx.ProfileID, price); err != nil {
+			return nil, err
+		}
+	}
+	receipt := &models.Receipt{
+		Type:     models.ReceiptBattlePass,
+		OfferID:  entry.OfferID,
+		Quantity: quantity,
+		Items:    []models.ReceiptItem{{TemplateID: entry.DevName, Quantity: quantity}},
+	}
+	if mtx {
+		receipt.CurrencyType = currencyType
+		receipt.Amount = -price
+	}
+
+	attributes := &athena.Stats.Attributes
+	if entry.OfferID == bp.TierOfferID {
+		start := attributes.BookLevel
+		attributes.BookLevel += quantity
+		loot, err := grantBattlePassTiers(ctx, athena, bp, start, attributes.BookLevel)
+		if err != nil {
+			return nil, err
+		}
+		addBattlePassGiftBox(ctx, "GiftBox:gb_battlepass", loot)
+		ctx.StatModified("athena", "book_level", attributes.BookLevel)
+		return receipt, nil
+	}
+
+	season := offerSeason(entry.OfferID)
+	tokenID := fmt.Sprintf("Token:Athena_S%d_NoBattleBundleOption_Token", season)
+	token := models.NewProfileItem(fmt.Sprintf("Token:athena_s%d_nobattlebundleoption_token", season))
+	token.Attributes.Level = 1
+	token.Attributes.ItemSeen = true
+	wallet, err := ctx.Wallet(ctx.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+	wallet.Items.Set(tokenID, token)
+	ctx.ItemAdded(ctx.ProfileID, tokenID, token)
+
+	attributes.BookPurchased = true
+	if entry.OfferID == bp.BattleBundleOfferID {
+		attributes.BookLevel = min(attributes.BookLevel+25, 100)
+	}
+	loot, err := grantBattlePassTiers(ctx, athena, bp, 0, attributes.BookLevel)
+	if err != nil {
+		return nil, err
+	}
+	addBattlePassGiftBox(ctx, "GiftBox:gb_battlepasspurchased", loot)
+	ctx.StatModified("athena", "book_purchased", true)
+	ctx.StatModified("athena", "book_level", attributes.BookLevel)
+	return receipt, nil
+}
+
+// offerSeason reads the season from the first number in an offer id.
+func offerSeason(offerID string) int {
+	start := strings.IndexAny(offerID, "0123456789")
+	if start < 0 {
+		return 0
+	}
+	end := start
+	for end < len(offerID) && offerID[end] >= '0' && offerID[end] <= '9' {
+		end++
+	}
+	season, _ := strconv.Atoi(offerID[start:end])
+	return season
+}
+
+// grantBattlePassTiers grants the free and paid rewards of tiers [from, to).
+func grantBattlePassTiers(ctx *MCPContext, athena *models.AthenaProfile, bp *battlePassData, from, to int) ([]models.LootResult, error) {
+	loot := []models.LootResult{}
+	for tier := from; tier < to; tier++ {
+		for _, rewards := range [][]map[string]int{bp.FreeRewards, bp.PaidRewards} {
+			if tier >= len(rewards) {
+				continue
+			}
+			for templateID, amount := range rewards[tier] {
+				if err := grantBattlePassReward(ctx, athena, templateID, amount); err != nil {
+					return nil, err
+				}
+				loot = append(loot, models.LootResult{ItemType: templateID, ItemGuid: templateID, Quantity: amount})
+			}
+		}
+	}
+	return loot, nil
+}
+
+func grantBattlePassReward(ctx *MCPContext, athena *models.AthenaProfile, templateID string, amount int) error {
+	attributes := &athena.Stats.Attributes
+	lower := strings.ToLower(templateID)
+	switch {
+	case lower == "token:athenaseasonxpboost":
+		attributes.SeasonMatchBoost += float64(amount)
+		ctx.StatModified("athena", "season_match_boost", attributes.SeasonMatchBoost)
+	case lower == "token:athenaseasonfriendxpboost":
+		attributes.SeasonFriendMatchBoost += float64(amount)
+		ctx.StatModified("athena", "season_friend_match_boost", attributes.SeasonFriendMatchBoost)
+	case strings.HasPrefix(lower, "currency:mtx"):
+		if _, err := creditMtx(ctx, ctx.ProfileID, amount); err != nil {
+			return err
+		}
+	case strings.HasPrefix(lower, "homebasebanner"):
+		wallet, err := ctx.Wallet(ctx.ProfileID)
+		if err != nil {
+			return err
+		}
+		grantOrRenew(ctx, ctx.ProfileID, &wallet.Items, templateID, models.NewProfileItem(templateID))
+	case strings.HasPrefix(lower, "athena"):
+		item := models.NewProfileItem(templateID)
+		item.Attributes.Level = 1
+		item.Attributes.Variants = []models.ItemVariant{}
+		item.Quantity = amount
+		grantOrRenew(ctx, "athena", &athena.Items, templateID, item)
+	}
+	return nil
+}
+
+// grantOrRenew marks the owned copies of templateID as unseen, or adds item
+// when there are none.
+func grantOrRenew(ctx *MCPContext, profileID string, items *models.ProfileItems, templateID string, item *models.ProfileItem) {
+	renewed := false
+	for _, id := range items.IDs() {
+		if !strings.EqualFold(items.TemplateID(id), templateID) {
+			continue
+		}
+		if owned, ok := items.Get(id); ok {
+			owned.Attributes.ItemSeen = false
+			ctx.ItemAttrChanged(profileID, id, "item_seen", false)
+			renewed = true
+		}
+	}
+	if renewed {
+		return
+	}
+	itemID := utils.GenerateRandomID()
+	items.Set(itemID, item)
+	ctx.ItemAdded(profileID, itemID, item)
+}
+
+func addBattlePassGiftBox(ctx *MCPContext, templateID string, loot []models.LootResult) {
+	wallet, err := ctx.Wallet(ctx.ProfileID)
+	if err != nil {
+		return
+	}
+	giftBox := models.NewProfileItem(templateID)
+	giftBox.Attributes.LootList = loot
+	giftBoxID := utils.GenerateRandomID()
+	wallet.Items.Set(giftBoxID, giftBox)
+	ctx.ItemAdded(ctx.ProfileID, giftBoxID, giftBox)
+}
+
+// purchaseStorefrontEntry grants an item shop offer and charges for it.
+// Bundles grant whatever the player doesn't own yet and are only refused when
+// every item is owned.
+func purchaseStorefrontEntry(ctx *MCPContext, athena *models.AthenaProfile, entry *utils.CatalogEntry) (*models.Receipt, error) {
+	receipt := &models.Receipt{Type: models.ReceiptPurchase, OfferID: entry.OfferID, Quantity: 1}
+	loot := []models.LootResult{}
+
+	owned := ownedTemplates(&athena.Items)
+	alreadyOwned := 0
+	for _, grant := range entry.ItemGrants {
+		templateID, _ := grant["templateId"].(string)
+		if owned[strings.ToLower(templateID)] {
+			alreadyOwned++
+			if alreadyOwned == len(entry.ItemGrants) {
+				return nil, NewMCPError("errors.com.epicgames.offer.already_owned",
+					"You have already bought this item before.",
+					nil, 1040, http.StatusBadRequest)
+			}
+			continue
+		}
+
+		itemID := utils.GenerateRandomID()
+		item := models.NewProfileItem(templateID)
+		item.Attributes.Variants = []models.ItemVariant{}
+		athena.Items.Set(itemID, item)
+		ctx.ItemAdded("athena", itemID, item)
+
+		loot = append(loot, models.LootResult{ItemType: templateID, ItemGuid: itemID, ItemProfile: "athena", Quantity: 1})
+		receipt.Items = append(receipt.Items, models.ReceiptItem{TemplateID: templateID, ItemID: itemID, Quantity: 1})
+	}
+	ctx.Notify(gin.H{
+		"type":       "CatalogPurchase",
+		"primary":    true,
+		"lootResult": gin.H{"items": loot},
+	})
+
+	purchase := &models.MtxPurchase{
+		PurchaseID:   utils.GenerateRandomID(),
+		OfferID:      "v2:/" + entry.OfferID,
+		PurchaseDate: time.Now().UTC().Format(time.RFC3339),
+		Fulfillments: []interface{}{},
+		LootResult:   loot,
+		Quantity:     receipt.Quantity,
+		Metadata:     map[string]interface{}{"refundable": entry.Refundable},
+	}
+	currencyType, currencySubType, price := offerPrice(entry)
+	switch {
+	case strings.EqualFold(currencyType, "MtxCurrency"):
+		if _, err := chargeMtx(ctx, ctx.ProfileID, price); err != nil {
+			return nil, err
+		}
+		purchase.TotalMtxPaid = price
+		receipt.CurrencyType = "MtxCurrency"
+	case strings.EqualFold(currencyType, "GameItem"):
+		if err := payWithGameItem(ctx, athena, currencySubType, price); err != nil {
+			return nil, err
+		}
+		// Refunds pay back V-Bucks, so purchases made with other currencies
+		// are listed but can't be refunded.
+		purchase.Metadata = map[string]interface{}{
+			"refundable":      false,
+			"currencyType":    "GameItem",
+			"currencySubType": currencySubType,
+			"price":           price,
+		}
+		receipt.CurrencyType = "GameItem"
+		receipt.CurrencySubType = currencySubType
+	default:
+		return receipt, nil
+	}
+
+	wallet, err := ctx.Wallet(ctx.ProfileID)
+	if err != nil {
+		return nil, err
+	}
+	history := &wallet.Stats.Attributes.MtxPurchaseHistory
+	history.Purchases = append(history.Purchases, purchase)
+	ctx.StatModified(ctx.ProfileID, "mtx_purchase_history", history)
+
+	receipt.ReceiptID = purchase.PurchaseID
+	receipt.Amount = -price
+	return receipt, nil
+}
+
+// payWithGameItem takes price of a currency item, e.g. event tokens, from
+// athena or else from the purchasing profile.
+func payWithGameItem(ctx *MCPContext, athena *models.AthenaProfile, currency string, price int) error {
+	if price <= 0 {
+		return nil
+	}
+	wallet, err := ctx.Wallet(ctx.ProfileID)
+	if err != nil {
+		return err
+	}
+
+	for _, owner := range []struct {
+		profileID string
+		items     *models.ProfileItems
+	}{{"athena", &athena.Items}, {ctx.ProfileID, &wallet.Items}} {
+		for _, id := range owner.items.IDs() {
+			if !strings.EqualFold(owner.items.TemplateID(id), currency) {
+				continue
+			}
+			item, ok := owner.items.Get(id)
+			if !ok {
+				continue
+			}
+			if item.Quantity < price {
+				return NewMCPError("errors.com.epicgames.currency.gameitem.insufficient",
+					"You don't have enough of the currency this item costs.",
+					nil, 1040, http.StatusBadRequest)
+			}
+			item.Quantity -= price
+			ctx.ItemQuantityChanged(owner.profileID, id, item.Quantity)
+			return nil
+		}
+	}
+	return NewMCPError("errors.com.epicgames.currency.gameitem.insufficient",
+		"You don't have enough of the currency this item costs.",
+		nil, 1040, http.StatusBadRequest)
+}
+
+type refundMtxPurchaseRequest struct {
+	PurchaseID string `json:"purchaseId" binding:"required"`
+}
+
+// refundMtxPurchase gives back the V-Bucks of a recent purchase and takes
+// back what it granted, using up one of the player's refund tickets.
+func refundMtxPurchase(ctx *MCPContext, req interface{}) error {
+	body := req.(*refundMtxPurchaseRequest)
+
+	wallet, err := ctx.Wallet(ctx.ProfileID)
+	if err != nil {
+		return err
+	}
+	history := &wallet.Stats.Attributes.MtxPurchaseHistory
+
+	// A refund ticket is in use until RefundTicketRestore has passed since
+	// the refund it paid for.
+	now := time.Now().UTC()
+	var purchase *models.MtxPurchase
+	ticketsInUse := 0
+	for _, p := range history.Purchases {
+		if p == nil {
+			continue
+		}
+		if refundDate, err := time.Parse(time.RFC3339, p.RefundDate); err == nil && now.Sub(refundDate) < utils.RefundTicketRestore() {
+			ticketsInUse++
+		}
+		if p.PurchaseID == body.PurchaseID {
+			purchase = p
+		}
+	}
+
+	if purchase == nil {
+		return NewMCPError("errors.com.epicgames.fortnite.id_invalid",
+			fmt.Sprintf("Purchase (id: '%s') not found", body.PurchaseID),
+			[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+	}
+	if purchase.RefundDate != "" {
+		return NewMCPError("errors.com.epicgames.fortnite.already_refunded",
+			fmt.Sprintf("Purchase (id: '%s') was already refunded", body.PurchaseID),
+			[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+	}
+	purchaseDate, err := time.Parse(time.RFC3339, purchase.PurchaseDate)
+	if err != nil || now.Sub(purchaseDate) > utils.RefundWindow() {
+		return NewMCPError("errors.com.epicgames.fortnite.refund_window_expired",
+			fmt.Sprintf("Purchases can only be refunded within %d days", int(utils.RefundWindow().Hours()/24)),
+			[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+	}
+	if !purchaseRefundable(purchase) {
+		return NewMCPError("errors.com.epicgames.fortnite.not_refundable",
+			fmt.Sprintf("Purchase (id: '%s') can not be refunded", body.PurchaseID),
+			[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+	}
+	ticketsLeft := utils.RefundTickets() - ticketsInUse
+	if ticketsLeft <= 0 {
+		return NewMCPError("errors.com.epicgames.fortnite.no_refund_tickets",
+			"You have no refund tickets left",
+			nil, 16027, http.StatusBadRequest)
+	}
+
+	// Only what the purchase granted goes back; bundle items the player
+	// already owned before buying aren't in its loot result.
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	refundedItems := []models.ReceiptItem{}
+	for _, loot := range purchase.LootResult {
+		if !athena.Items.Has(loot.ItemGuid) {
+			continue
+		}
+		refundedItems = append(refundedItems, models.ReceiptItem{
+			TemplateID: athena.Items.TemplateID(loot.ItemGuid),
+			ItemID:     loot.ItemGuid,
+			Quantity:   1,
+		})
+		athena.Items.Delete(loot.ItemGuid)
+		ctx.ItemRemoved("athena", loot.ItemGuid)
+	}
+	ctx.MarkChanged("athena")
+
+	// The V-Bucks paid are split evenly over the granted items, and only
+	// those still owned are refunded.
+	refundAmount := purchase.TotalMtxPaid
+	if granted := len(purchase.LootResult); granted > 0 {
+		if len(refundedItems) == 0 {
+			return NewMCPError("errors.com.epicgames.fortnite.not_refundable",
+				fmt.Sprintf("None of the items purchase (id: '%s') granted are owned anymore", body.PurchaseID),
+				[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+		}
+		refundAmount = purchase.TotalMtxPaid * len(refundedItems) / granted
+	}
+	// The V-Bucks have to have somewhere to go before anything is taken back.
+	if refundAmount > 0 {
+		credited, err := creditMtx(ctx, ctx.ProfileID, refundAmount)
+		if err != nil {
+			return err
+		}
+		if !credited {
+			return NewMCPError("errors.com.epicgames.currency.mtx.not_found",
+				fmt.Sprintf("No V-Bucks for platform %s to refund purchase (id: '%s') to", wallet.Stats.Attributes.CurrentMtxPlatform, body.PurchaseID),
+				[]string{body.PurchaseID}, 16027, http.StatusBadRequest)
+		}
+	}
+
+	purchase.RefundDate = now.Format(time.RFC3339)
+	history.RefundsUsed++
+	history.RefundCredits = ticketsLeft - 1
+	ctx.StatModified(ctx.ProfileID, "mtx_purchase_history", history)
+
+	// Purchases made before the quantity was stored were for one.
+	quantity := max(purchase.Quantity, 1)
+	ctx.AfterCommit(func() error {
+		utils.ReleaseOfferPurchase(ctx.AccountID, strings.TrimPrefix(purchase.OfferID, "v2:/"), purchaseDate, quantity)
+
+		// Purchases made before receipts were kept were all paid in V-Bucks.
+		currencyType := "MtxCurrency"
+		if original, err := utils.FindReceipt(ctx.AccountID, body.PurchaseID); err == nil && original.CurrencyType != "" {
+			currencyType = original.CurrencyType
+		}
+		utils.RecordReceipt(models.Receipt{
+			AccountID:        ctx.AccountID,
+			Type:             models.ReceiptRefund,
+			CurrencyType:     currencyType,
+			Amount:           refundAmount,
+			Items:            refundedItems,
+			RelatedReceiptID: body.PurchaseID,
+		})
+		return nil
+	})
+	return nil
+}
+
+// purchaseRefundable follows the refundable flag stored with the purchase,
+// falling back to the catalog entry for purchases made before it was stored.
+func purchaseRefundable(purchase *models.MtxPurchase) bool {
+	if refundable, ok := purchase.Metadata["refundable"].(bool); ok {
+		return refundable
+	}
+	_, entry := utils.GetOfferID(strings.TrimPrefix(purchase.OfferID, "v2:/"))
+	return entry == nil || entry.Refundable
+}
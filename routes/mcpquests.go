@@ -0,0 +1,243 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/utils"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterMCPOperation(MCPOperation{
+		Name:    "ClientQuestLogin",
+		Handler: clientQuestLogin,
+	})
+	RegisterMCPOperation(MCPOperation{
+		Name:       "FortRerollDailyQuest",
+		ProfileIDs: []string{"athena"},
+		Request:    func() interface{} { return &fortRerollDailyQuestRequest{} },
+		Handler:    fortRerollDailyQuest,
+	})
+}
+
+// clientQuestLogin hands out the day's daily quest on the first login of a
+// UTC day, clears dailies finished on earlier days and restores rerolls.
+func clientQuestLogin(ctx *MCPContext, req interface{}) error {
+	if ctx.ProfileID != "athena" {
+		return nil
+	}
+	quests, err := utils.CurrentQuests()
+	if err != nil {
+		return nil
+	}
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	manager := &athena.Stats.Attributes.QuestManager
+	lastLogin, _ := time.Parse(time.RFC3339, manager.DailyLoginInterval)
+	if lastLogin.Truncate(24 * time.Hour).Equal(now.Truncate(24 * time.Hour)) {
+		return nil
+	}
+
+	for _, id := range athena.Items.IDs() {
+		if _, daily := quests.FindDaily(athena.Items.TemplateID(id)); !daily {
+			continue
+		}
+		if quest, _ := athena.Items.Get(id); quest != nil && quest.Attributes.QuestState == "Claimed" {
+			athena.Items.Delete(id)
+			ctx.ItemRemoved("athena", id)
+		}
+	}
+
+	if len(activeDailies(athena, quests)) < quests.MaxDailyQuests {
+		if def := pickDailyQuest(athena, quests); def != nil {
+			id := utils.GenerateRandomID()
+			quest := newQuestItem(def, now)
+			athena.Items.Set(id, quest)
+			ctx.ItemAdded("athena", id, quest)
+		}
+	}
+
+	manager.DailyLoginInterval = now.Format(time.RFC3339)
+	manager.DailyQuestRerolls = quests.DailyRerolls
+	ctx.StatModified("athena", "quest_manager", manager)
+	return nil
+}
+
+type fortRerollDailyQuestRequest struct {
+	QuestID string `json:"questId" binding:"required"`
+}
+
+func fortRerollDailyQuest(ctx *MCPContext, req interface{}) error {
+	body := req.(*fortRerollDailyQuestRequest)
+
+	quests, err := utils.CurrentQuests()
+	if err != nil {
+		return NewMCPError("errors.com.epicgames.fortnite.quests_unavailable",
+			"No quests are configured for this season",
+			nil, 16099, http.StatusBadRequest)
+	}
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+
+	quest, ok := athena.Items.Get(body.QuestID)
+	if _, daily := quests.FindDaily(athena.Items.TemplateID(body.QuestID)); !ok || !daily || quest.Attributes.QuestState != "Active" {
+		return NewMCPError("errors.com.epicgames.fortnite.item_not_found",
+			fmt.Sprintf("Active daily quest '%s' not found", body.QuestID),
+			[]string{body.QuestID}, 16006, http.StatusBadRequest)
+	}
+
+	manager := &athena.Stats.Attributes.QuestManager
+	if manager.DailyQuestRerolls <= 0 {
+		return NewMCPError("errors.com.epicgames.fortnite.no_rerolls_remaining",
+			"No daily quest rerolls remaining",
+			nil, 16040, http.StatusBadRequest)
+	}
+
+	def := pickDailyQuest(athena, quests)
+	if def == nil {
+		return NewMCPError("errors.com.epicgames.fortnite.no_quests_available",
+			"No other daily quest is available",
+			nil, 16041, http.StatusBadRequest)
+	}
+
+	athena.Items.Delete(body.QuestID)
+	ctx.ItemRemoved("athena", body.QuestID)
+
+	id := utils.GenerateRandomID()
+	replacement := newQuestItem(def, time.Now().UTC())
+	athena.Items.Set(id, replacement)
+	ctx.ItemAdded("athena", id, replacement)
+
+	manager.DailyQuestRerolls--
+	ctx.StatModified("athena", "quest_manager", manager)
+	return nil
+}
+
+// activeDailies returns the ids of the daily quests still in progress.
+func activeDailies(athena *models.AthenaProfile, quests *utils.QuestData) []string {
+	var ids []string
+	for _, id := range athena.Items.IDs() {
+		if _, daily := quests.FindDaily(athena.Items.TemplateID(id)); !daily {
+			continue
+		}
+		if quest, _ := athena.Items.Get(id); quest != nil && quest.Attributes.QuestState == "Active" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// pickDailyQuest picks a random daily the player doesn't already hold.
+func pickDailyQuest(athena *models.AthenaProfile, quests *utils.QuestData) *utils.QuestDefinition {
+	held := map[string]bool{}
+	for _, id := range athena.Items.IDs() {
+		held[strings.ToLower(athena.Items.TemplateID(id))] = true
+	}
+	var candidates []*utils.QuestDefinition
+	for i := range quests.Daily {
+		if !held[strings.ToLower(quests.Daily[i].TemplateID)] {
+			candidates = append(candidates, &quests.Daily[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates[rand.Intn(len(candidates))]
+}
+
+func newQuestItem(def *utils.QuestDefinition, now time.Time) *models.ProfileItem {
+	quest := models.NewProfileItem(def.TemplateID)
+	quest.Attributes.Level = -1
+	quest.Attributes.QuestState = "Active"
+	quest.Attributes.CreationTime = now.Format(time.RFC3339)
+	quest.Attributes.LastStateChange = quest.Attributes.CreationTime
+	quest.Attributes.SetExtra("sent_new_notification", false)
+	quest.Attributes.SetExtra("xp_reward_scalar", 1)
+	for _, objective := range def.Objectives {
+		quest.Attributes.SetExtra("completion_"+objective.BackendName, 0)
+	}
+	return quest
+}
+
+// QuestProgress is one stat a dedicated server reports for a player.
+type QuestProgress struct {
+	StatName string `json:"statName" binding:"required"`
+	Count    int    `json:"count"`
+}
+
+// advanceQuests adds progress to every active quest with a matching
+// objective and claims quests whose objectives are all complete. It returns
+// whether anything changed and the XP the claimed quests award.
+func advanceQuests(athena *models.AthenaProfile, quests *utils.QuestData, progress []QuestProgress) (bool, int) {
+	changed := false
+	xp := 0
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	for _, id := range activeDailies(athena, quests) {
+		def, _ := quests.FindDaily(athena.Items.TemplateID(id))
+		quest, _ := athena.Items.Get(id)
+
+		complete := true
+		for _, objective := range def.Objectives {
+			key := "completion_" + objective.BackendName
+			current, _ := quest.Attributes.Extra(key).(float64)
+			done := int(current)
+			for _, p := range progress {
+				if strings.EqualFold(p.StatName, objective.BackendName) && p.Count > 0 && done < objective.Count {
+					done = min(done+p.Count, objective.Count)
+				}
+			}
+			if done != int(current) {
+				quest.Attributes.SetExtra(key, done)
+				changed = true
+			}
+			if done < objective.Count {
+				complete = false
+			}
+		}
+
+		if complete {
+			quest.Attributes.QuestState = "Claimed"
+			quest.Attributes.LastStateChange = now
+			xp += def.XPReward
+			changed = true
+		}
+	}
+	return changed, xp
+}
+
+type updateQuestProgressRequest struct {
+	Progress []QuestProgress `json:"progress" binding:"required,dive"`
+}
+
+// updateQuestProgress applies the quest stats a dedicated server reports for
+// a player after a match and levels them up with the XP earned.
+func updateQuestProgress(ctx *MCPContext, req interface{}) error {
+	body := req.(*updateQuestProgressRequest)
+
+	quests, err := utils.CurrentQuests()
+	if err != nil {
+		return NewMCPError("errors.com.epicgames.fortnite.quests_unavailable",
+			"No quests are configured for this season",
+			nil, 16099, http.StatusBadRequest)
+	}
+
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	changed, xp := advanceQuests(athena, quests, body.Progress)
+	if !changed {
+		return nil
+	}
+	return grantServerXP(ctx, athena, xp)
+}
@@ -0,0 +1,571 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"VentureBackend/static/profiles"
+	"VentureBackend/utils"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MCPOperation is one client MCP command served by MCPHandler.
+type MCPOperation struct {
+	Name string
+	// ProfileIDs lists the profiles the command may run against; empty allows
+	// every profile.
+	ProfileIDs []string
+	// Request returns a pointer to the body the command expects. Fields tagged
+	// binding:"required" are enforced. Nil means the body is ignored.
+	Request func() interface{}
+	// Handler changes the profiles through ctx. It may be nil for commands
+	// that only return the profile.
+	Handler func(ctx *MCPContext, req interface{}) error
+	// Dedicated commands are only sent by game servers, on the
+	// dedicated_server route.
+	Dedicated bool
+}
+
+var mcpOperations = map[string]*MCPOperation{}
+
+// RegisterMCPOperation adds a command to the dispatcher. Registering the same
+// name twice is a programming error.
+func RegisterMCPOperation(op MCPOperation) {
+	if _, exists := mcpOperations[op.Name]; exists {
+		panic("mcp operation registered twice: " + op.Name)
+	}
+	mcpOperations[op.Name] = &op
+}
+
+// FindMCPOperation returns the registered command or nil.
+func FindMCPOperation(name string) *MCPOperation {
+	return mcpOperations[name]
+}
+
+// MCPOperationNames lists the registered commands.
+func MCPOperationNames() []string {
+	names := make([]string, 0, len(mcpOperations))
+	for name := range mcpOperations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (op *MCPOperation) allows(profileID string) bool {
+	return len(op.ProfileIDs) == 0 || contains(op.ProfileIDs, profileID)
+}
+
+// MCPError is an Epic-style error a command handler returns.
+type MCPError struct {
+	Code    string
+	Message string
+	Vars    []string
+	Numeric int
+	Status  int
+}
+
+func (e *MCPError) Error() string {
+	return e.Message
+}
+
+func NewMCPError(code, message string, vars []string, numeric, status int) *MCPError {
+	return &MCPError{Code: code, Message: message, Vars: vars, Numeric: numeric, Status: status}
+}
+
+// MCPContext is the state one command runs against. Handlers read and change
+// profiles through the typed accessors and record what they changed. The
+// handler may run more than once when its write loses a revision race, so
+// anything outside the profiles document belongs in AfterCommit, or is undone
+// through OnRollback.
+type MCPContext struct {
+	Gin       *gin.Context
+	AccountID string
+	ProfileID string
+	Profiles  *models.Profiles
+	Version   utils.VersionInfo
+
+	typed         map[string]interface{}
+	changed       map[string]bool
+	changes       map[string][]gin.H
+	full          map[string]bool
+	notifications []gin.H
+	afterCommit   []func() error
+	rollback      []func()
+}
+
+func newMCPContext(c *gin.Context, accountID, profileID string) *MCPContext {
+	ctx := &MCPContext{Gin: c, AccountID: accountID, ProfileID: profileID}
+	if c != nil {
+		ctx.Version = utils.GetVersionInfo(c.Request)
+	}
+	ctx.reset(nil)
+	return ctx
+}
+
+// reset prepares the context for another attempt on a freshly read document,
+// undoing whatever the previous attempt reserved.
+func (ctx *MCPContext) reset(doc *models.Profiles) {
+	ctx.runRollback()
+	ctx.Profiles = doc
+	ctx.typed = map[string]interface{}{}
+	ctx.changed = map[string]bool{}
+	ctx.changes = map[string][]gin.H{}
+	ctx.full = map[string]bool{}
+	ctx.notifications = nil
+	ctx.afterCommit = nil
+}
+
+// OnRollback registers fn to undo a reservation made outside the profiles
+// document if this attempt is not written.
+func (ctx *MCPContext) OnRollback(fn func()) {
+	ctx.rollback = append(ctx.rollback, fn)
+}
+
+// AfterCommit registers fn to run once the profiles were written. The first
+// error it returns is the command's response.
+func (ctx *MCPContext) AfterCommit(fn func() error) {
+	ctx.afterCommit = append(ctx.afterCommit, fn)
+}
+
+// Notify adds an entry to the response's notifications.
+func (ctx *MCPContext) Notify(notification gin.H) {
+	ctx.notifications = append(ctx.notifications, notification)
+}
+
+// FullProfileUpdate sends profileID whole instead of as changes.
+func (ctx *MCPContext) FullProfileUpdate(profileID string) {
+	ctx.full[profileID] = true
+}
+
+// RefreshProfiles replaces the document with one written after the commit,
+// e.g. by an AfterCommit hook, and sends profileIDs whole.
+func (ctx *MCPContext) RefreshProfiles(doc *models.Profiles, profileIDs ...string) {
+	ctx.Profiles = doc
+	for _, profileID := range profileIDs {
+		ctx.full[profileID] = true
+	}
+}
+
+func (ctx *MCPContext) runRollback() {
+	for i := len(ctx.rollback) - 1; i >= 0; i-- {
+		ctx.rollback[i]()
+	}
+	ctx.rollback = nil
+}
+
+func (ctx *MCPContext) runAfterCommit() error {
+	ctx.rollback = nil
+	var first error
+	for _, fn := range ctx.afterCommit {
+		if err := fn(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Athena returns the typed athena profile.
+func (ctx *MCPContext) Athena() (*models.AthenaProfile, error) {
+	opened, err := ctx.open("athena", &models.AthenaProfile{})
+	if err != nil {
+		return nil, err
+	}
+	return opened.(*models.AthenaProfile), nil
+}
+
+// Wallet returns the profile holding the V-Bucks and receipts: common_core,
+// or profile0 on old builds.
+func (ctx *MCPContext) Wallet(profileID string) (*models.CommonCoreProfile, error) {
+	opened, err := ctx.open(profileID, &models.CommonCoreProfile{})
+	if err != nil {
+		return nil, err
+	}
+	return opened.(*models.CommonCoreProfile), nil
+}
+
+// CommonCore returns the typed common_core profile.
+func (ctx *MCPContext) CommonCore() (*models.CommonCoreProfile, error) {
+	return ctx.Wallet("common_core")
+}
+
+// Campaign returns the typed campaign profile.
+func (ctx *MCPContext) Campaign() (*models.CampaignProfile, error) {
+	opened, err := ctx.open("campaign", &models.CampaignProfile{})
+	if err != nil {
+		return nil, err
+	}
+	return opened.(*models.CampaignProfile), nil
+}
+
+// Profile returns any profile with untyped stats.
+func (ctx *MCPContext) Profile(profileID string) (*models.Profile, error) {
+	opened, err := ctx.open(profileID, &models.Profile{})
+	if err != nil {
+		return nil, err
+	}
+	return opened.(*models.Profile), nil
+}
+
+// Items returns the items of any profile, typed or not.
+func (ctx *MCPContext) Items(profileID string) (*models.ProfileItems, error) {
+	var opened interface{}
+	var err error
+	switch profileID {
+	case "athena":
+		opened, err = ctx.Athena()
+	case "common_core", "profile0":
+		opened, err = ctx.Wallet(profileID)
+	case "campaign":
+		opened, err = ctx.Campaign()
+	default:
+		opened, err = ctx.Profile(profileID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &opened.(interface{ Base() *models.ProfileBase }).Base().Items, nil
+}
+
+// open decodes profileID into fresh on first use and returns the cached copy
+// afterwards, so every accessor hands out the same value.
+func (ctx *MCPContext) open(profileID string, fresh interface{}) (interface{}, error) {
+	if opened, ok := ctx.typed[profileID]; ok {
+		if reflect.TypeOf(opened) != reflect.TypeOf(fresh) {
+			return nil, fmt.Errorf("profile %s is already open as %T", profileID, opened)
+		}
+		return opened, nil
+	}
+	raw, ok := ctx.Profiles.Profiles[profileID]
+	if !ok {
+		return nil, NewMCPError("errors.com.epicgames.modules.profiles.operation_forbidden",
+			fmt.Sprintf("Profile %s not found", profileID),
+			[]string{profileID}, 12813, http.StatusForbidden)
+	}
+	if err := models.DecodeProfile(raw, fresh); err != nil {
+		return nil, NewMCPError("errors.com.epicgames.modules.profiles.invalid_data",
+			fmt.Sprintf("Profile %s is malformed: %v", profileID, err),
+			[]string{profileID}, 12814, http.StatusInternalServerError)
+	}
+	ctx.typed[profileID] = fresh
+	return fresh, nil
+}
+
+// Change records a change to profileID and marks it for saving.
+func (ctx *MCPContext) Change(profileID string, change gin.H) {
+	ctx.changed[profileID] = true
+	ctx.changes[profileID] = append(ctx.changes[profileID], change)
+}
+
+// MarkChanged saves profileID without reporting a specific change.
+func (ctx *MCPContext) MarkChanged(profileID string) {
+	ctx.changed[profileID] = true
+}
+
+// ItemAttrChanged records the itemAttrChanged change the client expects.
+func (ctx *MCPContext) ItemAttrChanged(profileID, itemID, name string, value interface{}) {
+	ctx.Change(profileID, gin.H{
+		"changeType":     "itemAttrChanged",
+		"itemId":         itemID,
+		"attributeName":  name,
+		"attributeValue": value,
+	})
+}
+
+// ItemAdded records an added or replaced item.
+func (ctx *MCPContext) ItemAdded(profileID, itemID string, item *models.ProfileItem) {
+	ctx.Change(profileID, gin.H{
+		"changeType": "itemAdded",
+		"itemId":     itemID,
+		"item":       item,
+	})
+}
+
+// ItemRemoved records a removed item.
+func (ctx *MCPContext) ItemRemoved(profileID, itemID string) {
+	ctx.Change(profileID, gin.H{
+		"changeType": "itemRemoved",
+		"itemId":     itemID,
+	})
+}
+
+// ItemQuantityChanged records a new quantity for a stacked item.
+func (ctx *MCPContext) ItemQuantityChanged(profileID, itemID string, quantity int) {
+	ctx.Change(profileID, gin.H{
+		"changeType": "itemQuantityChanged",
+		"itemId":     itemID,
+		"quantity":   quantity,
+	})
+}
+
+// StatModified records the statModified change the client expects.
+func (ctx *MCPContext) StatModified(profileID, name string, value interface{}) {
+	ctx.Change(profileID, gin.H{
+		"changeType": "statModified",
+		"name":       name,
+		"value":      value,
+	})
+}
+
+// flush writes the changed typed profiles back into the document and drops
+// every typed copy, so helpers working on the raw document see the current
+// state and their edits aren't overwritten by a stale copy on commit.
+func (ctx *MCPContext) flush() error {
+	for profileID, typed := range ctx.typed {
+		if ctx.changed[profileID] {
+			encoded, err := models.EncodeProfile(typed)
+			if err != nil {
+				return err
+			}
+			ctx.Profiles.Profiles[profileID] = encoded
+		}
+		delete(ctx.typed, profileID)
+	}
+	return nil
+}
+
+// commit writes the typed profiles back into the document and returns the
+// profiles to save.
+func (ctx *MCPContext) commit() ([]string, error) {
+	if err := ctx.flush(); err != nil {
+		return nil, err
+	}
+	changed := make([]string, 0, len(ctx.changed))
+	for profileID := range ctx.changed {
+		if _, ok := ctx.Profiles.Profiles[profileID].(map[string]interface{}); ok {
+			changed = append(changed, profileID)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// mcpValidationError reports the body fields that failed validation by their
+// JSON names.
+func mcpValidationError(c *gin.Context, req interface{}, err error) {
+	var missing []string
+	var fieldErrs validator.ValidationErrors
+	if errors.As(err, &fieldErrs) {
+		reqType := reflect.TypeOf(req).Elem()
+		for _, fieldErr := range fieldErrs {
+			name := fieldErr.Field()
+			if field, ok := reqType.FieldByName(fieldErr.StructField()); ok {
+				if tag, _, _ := strings.Cut(field.Tag.Get("json"), ","); tag != "" {
+					name = tag
+				}
+			}
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		utils.CreateError(c,
+			"errors.com.epicgames.validation.validation_failed",
+			"Invalid request body",
+			nil, 1040, "Bad Request", 400)
+		return
+	}
+	utils.CreateError(c,
+		"errors.com.epicgames.validation.validation_failed",
+		"Validation Failed. ["+strings.Join(missing, ", ")+"] field(s) is missing.",
+		[]string{"[" + strings.Join(missing, ", ") + "]"}, 1040, "Bad Request", 400)
+}
+
+func MCPHandler(c *gin.Context) {
+	operation := c.Param("operation")
+	op := FindMCPOperation(operation)
+	if op == nil || op.Dedicated {
+		utils.CreateError(c,
+			"errors.com.epicgames.fortnite.operation_not_found",
+			fmt.Sprintf("Operation %s not valid", operation),
+			[]string{operation}, 16035, "NotFound", 404)
+		return
+	}
+	runMCPOperation(c, op, c.Param("accountId"), c.Query("profileId"))
+}
+
+// DedicatedServerHandler serves the commands game servers send about a
+// player. Anything that isn't a dedicated command only reads the profile.
+func DedicatedServerHandler(c *gin.Context) {
+	op := FindMCPOperation(c.Param("operation"))
+	if op == nil || !op.Dedicated {
+		op = FindMCPOperation("QueryProfile")
+	}
+	runMCPOperation(c, op, c.Param("accountId"), c.DefaultQuery("profileId", "athena"))
+}
+
+// runMCPOperation runs op against the account's profiles and writes the
+// result, repeating the command on a fresh copy when another request changed
+// the profiles in between.
+func runMCPOperation(c *gin.Context, op *MCPOperation, accountId, profileId string) {
+	var req interface{}
+	if op.Request != nil {
+		req = op.Request()
+		if err := c.ShouldBindJSON(req); err != nil {
+			mcpValidationError(c, req, err)
+			return
+		}
+	}
+
+	ctx := newMCPContext(c, accountId, profileId)
+	var baseRevisions map[string]utils.ProfileRevision
+
+	_, err := utils.UpdateProfiles(accountId, func(doc *models.Profiles) ([]string, error) {
+		ctx.reset(doc)
+		if !profiles.ValidateProfile(profileId, doc) {
+			return nil, NewMCPError("errors.com.epicgames.modules.profiles.operation_forbidden",
+				fmt.Sprintf("Unable to find template configuration for profile %s", profileId),
+				[]string{profileId}, 12813, http.StatusForbidden)
+		}
+		if !op.allows(profileId) {
+			return nil, NewMCPError("errors.com.epicgames.modules.profiles.invalid_command",
+				fmt.Sprintf("%s is not valid on %s profile", op.Name, profileId),
+				[]string{op.Name, profileId}, 12801, http.StatusBadRequest)
+		}
+		baseRevisions = utils.ProfileRevisions(doc)
+
+		if profileId == "athena" {
+			if err := ctx.prepareAthena(); err != nil {
+				return nil, err
+			}
+		}
+		if op.Handler != nil {
+			if err := op.Handler(ctx, req); err != nil {
+				return nil, err
+			}
+		}
+
+		changed, err := ctx.commit()
+		if err != nil {
+			return nil, NewMCPError("errors.com.epicgames.modules.profiles.invalid_data",
+				err.Error(), nil, 12814, http.StatusInternalServerError)
+		}
+		return changed, nil
+	})
+	if err == nil {
+		err = ctx.runAfterCommit()
+	} else {
+		ctx.runRollback()
+	}
+	if err != nil {
+		mcpErrorResponse(c, accountId, profileId, err)
+		return
+	}
+
+	qRev, _ := strconv.Atoi(c.DefaultQuery("rvn", "-1"))
+	base := baseRevisions[profileId]
+	revisionCheck := base.Rvn
+	if ctx.Version.Build >= 12.20 {
+		revisionCheck = base.CommandRevision
+	}
+	revisions := utils.ProfileRevisions(ctx.Profiles)
+	response := mcpProfileResponse(ctx, profileId, base, revisions[profileId], qRev != revisionCheck || ctx.full[profileId])
+
+	multiUpdate := []gin.H{}
+	for _, changedId := range ctx.updatedProfiles() {
+		if changedId == profileId {
+			continue
+		}
+		multiUpdate = append(multiUpdate, mcpProfileResponse(ctx, changedId, baseRevisions[changedId], revisions[changedId], ctx.full[changedId]))
+	}
+	if len(ctx.notifications) > 0 {
+		response["notifications"] = ctx.notifications
+	}
+	response["serverTime"] = time.Now().UTC().Format(time.RFC3339)
+	response["multiUpdate"] = multiUpdate
+	response["responseVersion"] = 1
+
+	c.JSON(http.StatusOK, response)
+}
+
+// prepareAthena fills in the athena stats the client relies on before every
+// command: the season it is playing and an applied loadout.
+func (ctx *MCPContext) prepareAthena() error {
+	athena, err := ctx.Athena()
+	if err != nil {
+		return err
+	}
+	attributes := &athena.Stats.Attributes
+	if attributes.LastAppliedLoadout == "" && len(attributes.Loadouts) > 0 && attributes.Loadouts[0] != "" {
+		attributes.LastAppliedLoadout = attributes.Loadouts[0]
+		ctx.MarkChanged("athena")
+	}
+	if ctx.Version.Season > 0 && attributes.SeasonNum != ctx.Version.Season {
+		attributes.SeasonNum = ctx.Version.Season
+		ctx.MarkChanged("athena")
+	}
+	return ctx.flush()
+}
+
+// updatedProfiles lists the profiles the response reports, in a stable order.
+func (ctx *MCPContext) updatedProfiles() []string {
+	ids := make([]string, 0, len(ctx.changed)+len(ctx.full))
+	for profileID := range ctx.changed {
+		ids = append(ids, profileID)
+	}
+	for profileID := range ctx.full {
+		if !ctx.changed[profileID] {
+			ids = append(ids, profileID)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// mcpErrorResponse answers a command that failed or couldn't be written.
+func mcpErrorResponse(c *gin.Context, accountId, profileId string, err error) {
+	var mcpErr *MCPError
+	switch {
+	case errors.As(err, &mcpErr):
+		utils.CreateError(c, mcpErr.Code, mcpErr.Message, mcpErr.Vars, mcpErr.Numeric,
+			http.StatusText(mcpErr.Status), mcpErr.Status)
+	case errors.Is(err, mongo.ErrNoDocuments):
+		utils.CreateError(c,
+			"errors.com.epicgames.modules.profiles.operation_forbidden",
+			fmt.Sprintf("Unable to find template configuration for profile %s", profileId),
+			[]string{profileId}, 12813, "Forbidden", 403)
+	case errors.Is(err, utils.ErrProfileRevisionMismatch):
+		utils.Backend.Logf("Gave up writing profile %s of %s after %d revision conflicts", profileId, accountId, utils.ProfileWriteAttempts)
+		utils.CreateError(c,
+			"errors.com.epicgames.modules.profiles.profile_revision_mismatch",
+			"Profile "+profileId+" of account "+accountId+" was changed by another request, please retry",
+			[]string{profileId, accountId}, 12805, "Conflict", http.StatusConflict)
+	default:
+		utils.Error.Logf("Failed to update profile %s of %s: %v", profileId, accountId, err)
+		utils.CreateError(c,
+			"errors.com.epicgames.modules.profiles.update_failed",
+			"Failed to update profile",
+			nil, 50001, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// mcpProfileResponse describes one profile after the command, either as the
+// recorded changes or, when the client is out of date, the full profile.
+func mcpProfileResponse(ctx *MCPContext, profileID string, base, current utils.ProfileRevision, full bool) gin.H {
+	changes := ctx.changes[profileID]
+	if changes == nil {
+		changes = []gin.H{}
+	}
+	if full {
+		changes = []gin.H{{
+			"changeType": "fullProfileUpdate",
+			"profile":    ctx.Profiles.Profiles[profileID],
+		}}
+	}
+
+	return gin.H{
+		"profileRevision":            current.Rvn,
+		"profileId":                  profileID,
+		"profileChangesBaseRevision": base.Rvn,
+		"profileChanges":             changes,
+		"profileCommandRevision":     current.CommandRevision,
+	}
+}
@@ -0,0 +1,161 @@
+package routes
+
+import (
+	"VentureBackend/static/models"
+	"testing"
+)
+
+func testMCPContext(profileID string) *MCPContext {
+	ctx := newMCPContext(nil, "account", profileID)
+	ctx.reset(&models.Profiles{
+		AccountID: "account",
+		Profiles: map[string]interface{}{
+			"athena": map[string]interface{}{
+				"rvn":             float64(5),
+				"commandRevision": float64(5),
+				"stats":           map[string]interface{}{"attributes": map[string]interface{}{"level": float64(3)}},
+				"items": map[string]interface{}{
+					"item1": map[string]interface{}{
+						"templateId": "AthenaCharacter:cid_001",
+						"attributes": map[string]interface{}{"item_seen": false},
+						"quantity":   float64(1),
+					},
+				},
+			},
+			"common_core": map[string]interface{}{
+				"rvn":             float64(2),
+				"commandRevision": float64(2),
+				"items":           map[string]interface{}{},
+			},
+		},
+	})
+	return ctx
+}
+
+func TestRegisterMCPOperationTwicePanics(t *testing.T) {
+	const name = "TestOnlyOperation"
+	RegisterMCPOperation(MCPOperation{Name: name})
+	defer delete(mcpOperations, name)
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("registering %s twice did not panic", name)
+		}
+	}()
+	RegisterMCPOperation(MCPOperation{Name: name})
+}
+
+func TestFindMCPOperation(t *testing.T) {
+	for _, name := range []string{"QueryProfile", "MarkItemSeen", "SetItemFavoriteStatusBatch"} {
+		if FindMCPOperation(name) == nil {
+			t.Errorf("%s is not registered", name)
+		}
+	}
+	if FindMCPOperation("NoSuchOperation") != nil {
+		t.Errorf("unknown operation found")
+	}
+
+	op := FindMCPOperation("SetItemFavoriteStatusBatch")
+	if !op.allows("athena") || op.allows("common_core") {
+		t.Errorf("SetItemFavoriteStatusBatch profiles = %v", op.ProfileIDs)
+	}
+	if !FindMCPOperation("MarkItemSeen").allows("common_core") {
+		t.Errorf("MarkItemSeen should run on every profile")
+	}
+}
+
+func TestMCPCommitSavesOnlyChangedProfiles(t *testing.T) {
+	ctx := testMCPContext("athena")
+	op := FindMCPOperation("MarkItemSeen")
+	req := &markItemSeenRequest{ItemIds: []string{"item1", "missing"}}
+	if err := op.Handler(ctx, req); err != nil {
+		t.Fatalf("MarkItemSeen: %v", err)
+	}
+
+	if got := len(ctx.changes["athena"]); got != 1 {
+		t.Fatalf("recorded %d changes, want 1", got)
+	}
+
+	changed, err := ctx.commit()
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if len(changed) != 1 || changed[0] != "athena" {
+		t.Fatalf("commit saves %v, want only athena", changed)
+	}
+	athena := ctx.Profiles.Profiles["athena"].(map[string]interface{})
+	item := athena["items"].(map[string]interface{})["item1"].(map[string]interface{})
+	if item["attributes"].(map[string]interface{})["item_seen"] != true {
+		t.Errorf("item1 = %v", item)
+	}
+	if athena["stats"] == nil {
+		t.Errorf("stats lost when writing athena back")
+	}
+	if athena["rvn"] != float64(5) {
+		t.Errorf("commit bumped the revision itself: %v", athena["rvn"])
+	}
+}
+
+func TestMCPCommitWithoutChanges(t *testing.T) {
+	ctx := testMCPContext("athena")
+	if _, err := ctx.Athena(); err != nil {
+		t.Fatalf("Athena: %v", err)
+	}
+	changed, err := ctx.commit()
+	if err != nil || len(changed) != 0 {
+		t.Errorf("commit without changes = %v, %v", changed, err)
+	}
+}
+
+func TestMCPContextOpenKeepsType(t *testing.T) {
+	ctx := testMCPContext("athena")
+	first, err := ctx.Athena()
+	if err != nil {
+		t.Fatalf("Athena: %v", err)
+	}
+	second, err := ctx.Athena()
+	if err != nil || first != second {
+		t.Errorf("Athena opened twice returned different copies")
+	}
+	if _, err := ctx.Profile("athena"); err == nil {
+		t.Errorf("opening athena untyped after typed did not fail")
+	}
+	if _, err := ctx.Profile("campaign"); err == nil {
+		t.Errorf("missing profile opened")
+	}
+}
+
+func TestMCPContextResetRollsBack(t *testing.T) {
+	ctx := testMCPContext("athena")
+	released := 0
+	ctx.OnRollback(func() { released++ })
+	ctx.AfterCommit(func() error { t.Errorf("after commit hook of a discarded attempt ran"); return nil })
+	ctx.MarkChanged("athena")
+
+	ctx.reset(ctx.Profiles)
+	if released != 1 {
+		t.Errorf("reservation released %d times on reset, want 1", released)
+	}
+	if len(ctx.changed) != 0 {
+		t.Errorf("changes of the previous attempt kept: %v", ctx.changed)
+	}
+	if err := ctx.runAfterCommit(); err != nil {
+		t.Errorf("runAfterCommit: %v", err)
+	}
+	ctx.runRollback()
+	if released != 1 {
+		t.Errorf("reservation released again after reset")
+	}
+}
+
+func TestMCPContextAfterCommitKeepsReservations(t *testing.T) {
+	ctx := testMCPContext("athena")
+	ctx.OnRollback(func() { t.Errorf("reservation released after commit") })
+	ran := false
+	ctx.AfterCommit(func() error { ran = true; return nil })
+
+	if err := ctx.runAfterCommit(); err != nil || !ran {
+		t.Errorf("runAfterCommit = %v, ran %v", err, ran)
+	}
+	ctx.runRollback()
+}
@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"VentureBackend/static/tokens"
+	"VentureBackend/utils"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -60,7 +62,7 @@ func RegisterMiscRoutes(r *gin.Engine) {
 	r.GET("/fortnite/api/game/v2/twitch/:any", twitchHandler)
 	r.GET("/fortnite/api/game/v2/world/info", worldInfoHandler)
 	r.GET("/presence/api/v1/_/:any/last-online", lastOnlineHandler)
-	r.GET("/fortnite/api/receipts/v1/account/:accountId/receipts", receiptsHandler)
+	r.GET("/fortnite/api/receipts/v1/account/:accountId/receipts", tokens.VerifyToken(), receiptsHandler)
 	r.GET("/fortnite/api/game/v2/leaderboards/cohort/:any", leaderboardsHandler)
 	r.POST("/api/v1/assets/Fortnite/:param1/:param2", assetsFortniteHandler)
 	r.GET("/region", regionHandler)
@@ -188,7 +190,30 @@ func lastOnlineHandler(c *gin.Context) {
 }
 
 func receiptsHandler(c *gin.Context) {
-	c.JSON(http.StatusOK, []interface{}{})
+	accountId := c.Param("accountId")
+	if !ownsAccount(c, accountId) {
+		return
+	}
+
+	receipts, err := utils.FindReceipts(accountId)
+	if err != nil {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.server_error",
+			"Failed to load receipts.",
+			[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+		return
+	}
+
+	response := []gin.H{}
+	for _, receipt := range receipts {
+		response = append(response, gin.H{
+			"appStore":    "EpicPurchasingService",
+			"appStoreId":  receipt.ReceiptID,
+			"receiptId":   receipt.ReceiptID,
+			"receiptInfo": string(receipt.Type),
+		})
+	}
+	c.JSON(http.StatusOK, response)
 }
 
 func leaderboardsHandler(c *gin.Context) {
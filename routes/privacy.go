@@ -1,15 +1,15 @@
 package routes
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"os"
 
+	"VentureBackend/static/models"
 	"VentureBackend/static/tokens"
 	"VentureBackend/utils"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
@@ -69,12 +69,6 @@ func postPrivacy(c *gin.Context) {
 		return
 	}
 
-	profile, err := utils.FindProfileByAccountID(accountId)
-	if err != nil {
-		c.Status(http.StatusBadRequest)
-		return
-	}
-
 	var body struct {
 		OptOutOfPublicLeaderboards bool `json:"optOutOfPublicLeaderboards"`
 	}
@@ -83,19 +77,25 @@ func postPrivacy(c *gin.Context) {
 		return
 	}
 
-	if athena, ok := profile.Profiles["athena"].(map[string]interface{}); ok {
-		if stats, ok := athena["stats"].(map[string]interface{}); ok {
-			if attributes, ok := stats["attributes"].(map[string]interface{}); ok {
-				attributes["optOutOfPublicLeaderboards"] = body.OptOutOfPublicLeaderboards
+	profile, err := utils.UpdateProfiles(accountId, func(doc *models.Profiles) ([]string, error) {
+		if athena, ok := doc.Profiles["athena"].(map[string]interface{}); ok {
+			if stats, ok := athena["stats"].(map[string]interface{}); ok {
+				if attributes, ok := stats["attributes"].(map[string]interface{}); ok {
+					attributes["optOutOfPublicLeaderboards"] = body.OptOutOfPublicLeaderboards
+					return []string{"athena"}, nil
+				}
 			}
 		}
-	}
-
-	_, err = ProfileCollection.UpdateOne(context.Background(), bson.M{
-		"accountId": accountId,
-	}, bson.M{
-		"$set": bson.M{"profiles": profile.Profiles},
+		return nil, nil
 	})
+	if errors.Is(err, utils.ErrProfileRevisionMismatch) {
+		c.Status(http.StatusConflict)
+		return
+	}
+	if profile == nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		c.Status(http.StatusInternalServerError)
 		return
@@ -18,6 +18,14 @@ func RegisterStorefrontRoutes(router *gin.Engine) {
 
 func CatalogHandler(c *gin.Context) {
 	catalog := utils.GetItemShop()
+	if decoded := tokens.FromContext(c); decoded != nil {
+		if err := utils.ApplyPurchaseLimits(catalog, decoded.AccountID); err != nil {
+			utils.Error.Logf("Failed to count purchases of %s: %v", decoded.AccountID, err)
+		}
+		if err := utils.ApplyBundlePricing(catalog, decoded.AccountID); err != nil {
+			utils.Error.Logf("Failed to price bundles for %s: %v", decoded.AccountID, err)
+		}
+	}
 	c.JSON(http.StatusOK, catalog)
 }
 
@@ -17,9 +17,9 @@ func RegisterTimelineRoutes(router *gin.Engine) {
 func GetTimeline(c *gin.Context) {
 	memory := utils.GetVersionInfo(c.Request)
 
-	todayAtMidnight := time.Now().Truncate(24 * time.Hour).Add(24 * time.Hour)
-	todayOneMinuteBeforeMidnight := todayAtMidnight.Add(-1 * time.Minute)
-	isoDate := todayOneMinuteBeforeMidnight.Format(time.RFC3339)
+	// The same schedule as the catalog, so the client's countdown ends when
+	// the shop changes.
+	isoDate := utils.ShopEnd(time.Now()).Add(-1 * time.Minute).Format(time.RFC3339)
 
 	seasonStr := strconv.Itoa(memory.Season)
 
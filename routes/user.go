@@ -1,6 +1,9 @@
 package routes
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"net/http"
 	"regexp"
 	"strings"
@@ -16,6 +19,7 @@ import (
 	"VentureBackend/static/models"
 	"VentureBackend/static/tokens"
 	"VentureBackend/utils"
+	"VentureBackend/ws/xmpp"
 )
 
 func RegisterAccountRoutes(r *gin.Engine, userCollection *mongo.Collection) {
@@ -194,14 +198,14 @@ func RegisterAccountRoutes(r *gin.Engine, userCollection *mongo.Collection) {
 			"email":                      "[hidden]@" + strings.SplitN(user.Email, "@", 2)[1],
 			"failedLoginAttempts":        0,
 			"lastLogin":                  time.Now().Format(time.RFC3339),
-			"numberOfDisplayNameChanges": 0,
+			"numberOfDisplayNameChanges": len(user.NameHistory),
 			"ageGroup":                   "UNKNOWN",
 			"headless":                   false,
 			"country":                    "US",
 			"lastName":                   "Server",
 			"preferredLanguage":          "en",
-			"canUpdateDisplayName":       false,
-			"tfaEnabled":                 false,
+			"canUpdateDisplayName":       !time.Now().Before(utils.NextDisplayNameChange(user)),
+			"tfaEnabled":                 user.TwoFactor.Enabled,
 			"emailVerified":              true,
 			"minorVerified":              false,
 			"minorExpected":              false,
@@ -211,6 +215,150 @@ func RegisterAccountRoutes(r *gin.Engine, userCollection *mongo.Collection) {
 		})
 	})
 
+	r.PUT("/account/api/public/account/:accountId", tokens.VerifyToken(), func(c *gin.Context) {
+		accountId := c.Param("accountId")
+		if !ownsAccount(c, accountId) {
+			return
+		}
+
+		var body struct {
+			DisplayName string `json:"displayName"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil || body.DisplayName == "" {
+			utils.CreateError(c,
+				"errors.com.epicgames.bad_request",
+				"Required String parameter 'displayName' is invalid or not present",
+				[]string{"displayName"}, 1001, "parameter_invalid", http.StatusBadRequest)
+			return
+		}
+
+		user, err := utils.FindUserByAccountID(accountId)
+		if err != nil || user == nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.account_not_found",
+				"Account not found",
+				nil, 18007, "account_not_found", http.StatusNotFound)
+			return
+		}
+
+		if !changeDisplayName(c, user, body.DisplayName) {
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"accountInfo": gin.H{
+				"id":                         user.AccountID,
+				"displayName":                user.Username,
+				"numberOfDisplayNameChanges": len(user.NameHistory) + 1,
+				"canUpdateDisplayName":       false,
+				"lastDisplayNameChange":      user.LastNameChange.Format(time.RFC3339),
+			},
+		})
+	})
+
+	r.POST("/account/api/public/account/:accountId/deviceAuth", tokens.VerifyToken(), tokens.RequireScope("account:device_auth"), func(c *gin.Context) {
+		accountId := c.Param("accountId")
+		if !ownsAccount(c, accountId) {
+			return
+		}
+
+		secret, err := generateDeviceSecret()
+		if err != nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.server_error",
+				"Failed to create device auth.",
+				[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+			return
+		}
+		secretHash, err := utils.HashSecret(secret)
+		if err != nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.server_error",
+				"Failed to create device auth.",
+				[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+			return
+		}
+
+		device := models.DeviceAuth{
+			DeviceID:   strings.ReplaceAll(utils.GenerateRandomID(), "-", ""),
+			SecretHash: secretHash,
+			UserAgent:  c.GetHeader("User-Agent"),
+			CreatedIP:  c.ClientIP(),
+			Created:    time.Now().UTC(),
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_, err = userCollection.UpdateOne(ctx,
+			bson.M{"accountId": accountId},
+			bson.M{"$push": bson.M{"deviceAuths": device}})
+		if err != nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.server_error",
+				"Failed to create device auth.",
+				[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+			return
+		}
+
+		response := deviceAuthResponse(accountId, device)
+		response["secret"] = secret
+		c.JSON(http.StatusOK, response)
+	})
+
+	r.GET("/account/api/public/account/:accountId/deviceAuth", tokens.VerifyToken(), tokens.RequireScope("account:device_auth"), func(c *gin.Context) {
+		accountId := c.Param("accountId")
+		if !ownsAccount(c, accountId) {
+			return
+		}
+
+		user, err := utils.FindUserByAccountID(accountId)
+		if err != nil || user == nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.account_not_found",
+				"Account not found",
+				nil, 18007, "account_not_found", http.StatusNotFound)
+			return
+		}
+
+		response := []gin.H{}
+		for _, device := range user.DeviceAuths {
+			response = append(response, deviceAuthResponse(accountId, device))
+		}
+		c.JSON(http.StatusOK, response)
+	})
+
+	r.DELETE("/account/api/public/account/:accountId/deviceAuth/:deviceId", tokens.VerifyToken(), tokens.RequireScope("account:device_auth"), func(c *gin.Context) {
+		accountId := c.Param("accountId")
+		deviceId := c.Param("deviceId")
+		if !ownsAccount(c, accountId) {
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		result, err := userCollection.UpdateOne(ctx,
+			bson.M{"accountId": accountId},
+			bson.M{"$pull": bson.M{"deviceAuths": bson.M{"deviceId": deviceId}}})
+		if err != nil {
+			utils.CreateError(c,
+				"errors.com.epicgames.common.server_error",
+				"Failed to delete device auth.",
+				[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+			return
+		}
+		if result.ModifiedCount == 0 {
+			utils.CreateError(c,
+				"errors.com.epicgames.account.device_auth.not_found",
+				"Sorry, we couldn't find a device auth with id "+deviceId,
+				[]string{deviceId}, 18065, "not_found", http.StatusNotFound)
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	})
+
 	r.GET("/sdk/v1/*filepath", func(c *gin.Context) {
 		sdkJSON, err := utils.LoadJSON("./static/responses/sdkv1.json")
 		if err != nil {
@@ -279,3 +427,84 @@ func RegisterAccountRoutes(r *gin.Engine, userCollection *mongo.Collection) {
 		c.String(http.StatusOK, "true")
 	})
 }
+
+// ownsAccount rejects requests whose access token belongs to another account.
+func ownsAccount(c *gin.Context, accountId string) bool {
+	decoded := tokens.FromContext(c)
+	if decoded == nil || decoded.AccountID != accountId {
+		utils.CreateError(c,
+			"errors.com.epicgames.common.missing_permission",
+			"Sorry your login does not posses the permissions to access this account.",
+			[]string{accountId}, 1023, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func generateDeviceSecret() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func deviceAuthResponse(accountId string, device models.DeviceAuth) gin.H {
+	response := gin.H{
+		"deviceId":  device.DeviceID,
+		"accountId": accountId,
+		"userAgent": device.UserAgent,
+		"created": gin.H{
+			"location":  "",
+			"ipAddress": device.CreatedIP,
+			"dateTime":  device.Created.Format(time.RFC3339),
+		},
+	}
+	if !device.LastAccess.IsZero() {
+		response["lastAccess"] = gin.H{
+			"location":  "",
+			"ipAddress": "",
+			"dateTime":  device.LastAccess.Format(time.RFC3339),
+		}
+	}
+	return response
+}
+
+// changeDisplayName applies a rename requested through the API and writes the
+// matching Epic error when it is refused.
+func changeDisplayName(c *gin.Context, user *models.User, displayName string) bool {
+	err := utils.ChangeDisplayName(user, displayName, user.AccountID)
+	var cooldown utils.DisplayNameCooldownError
+	switch {
+	case err == nil:
+		xmpp.RefreshDisplayName(user.AccountID, user.Username)
+		return true
+	case errors.As(err, &cooldown):
+		next := cooldown.NextChange.UTC().Format(time.RFC3339)
+		utils.CreateError(c,
+			"errors.com.epicgames.account.display_name_change_cooldown",
+			"Sorry, you can't change your display name again until "+next,
+			[]string{next}, 18091, "display_name_change_cooldown", http.StatusBadRequest)
+	case errors.Is(err, utils.ErrDisplayNameTaken):
+		utils.CreateError(c,
+			"errors.com.epicgames.account.display_name_taken",
+			"Sorry, the display name "+displayName+" is already taken",
+			[]string{displayName}, 18006, "display_name_taken", http.StatusConflict)
+	case errors.Is(err, utils.ErrDisplayNameChanged):
+		utils.CreateError(c,
+			"errors.com.epicgames.common.concurrent_modification_error",
+			"Your display name was changed by another request, please retry",
+			[]string{}, 1042, "Conflict", http.StatusConflict)
+	case errors.Is(err, utils.ErrDisplayNameInvalid), errors.Is(err, utils.ErrDisplayNameUnchanged):
+		utils.CreateError(c,
+			"errors.com.epicgames.account.invalid_display_name",
+			"Sorry, the display name "+displayName+" is invalid",
+			[]string{displayName}, 18093, "invalid_display_name", http.StatusBadRequest)
+	default:
+		utils.CreateError(c,
+			"errors.com.epicgames.common.server_error",
+			"Failed to change display name.",
+			[]string{}, 1000, "InternalServerError", http.StatusInternalServerError)
+	}
+	return false
+}
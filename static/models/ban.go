@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type BanType string
+
+const (
+	BanAccount BanType = "account"
+	BanIP      BanType = "ip"
+	BanHWID    BanType = "hwid"
+)
+
+// Ban blocks an account id, IP or hardware id. A nil ExpiresAt is permanent.
+// AccountID records who the ban was issued against, also for IP/HWID bans.
+type Ban struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Type       BanType            `bson:"type" json:"type"`
+	Value      string             `bson:"value" json:"value"`
+	AccountID  string             `bson:"accountId,omitempty" json:"accountId,omitempty"`
+	Reason     string             `bson:"reason" json:"reason"`
+	IssuedBy   string             `bson:"issuedBy" json:"issuedBy"`
+	Created    time.Time          `bson:"created" json:"created"`
+	ExpiresAt  *time.Time         `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	AppealNote string             `bson:"appealNote,omitempty" json:"appealNote,omitempty"`
+	Lifted     bool               `bson:"lifted" json:"lifted"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Gift is one GiftCatalogEntry delivery to one receiver. It keeps what was
+// charged and granted so an admin can reverse it.
+type Gift struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SenderID      string             `bson:"senderId" json:"senderId"`
+	ReceiverID    string             `bson:"receiverId" json:"receiverId"`
+	OfferID       string             `bson:"offerId" json:"offerId"`
+	Price         int                `bson:"price" json:"price"`
+	CurrencyType  string             `bson:"currencyType" json:"currencyType"`
+	ChargedItemID string             `bson:"chargedItemId,omitempty" json:"chargedItemId,omitempty"`
+	GiftBoxID     string             `bson:"giftBoxId" json:"giftBoxId"`
+	GrantedItems  []GiftedItem       `bson:"grantedItems" json:"grantedItems"`
+	Message       string             `bson:"message" json:"message"`
+	Created       time.Time          `bson:"created" json:"created"`
+	Reversed      bool               `bson:"reversed" json:"reversed"`
+	ReversedAt    *time.Time         `bson:"reversedAt,omitempty" json:"reversedAt,omitempty"`
+	ReversedBy    string             `bson:"reversedBy,omitempty" json:"reversedBy,omitempty"`
+}
+
+type GiftedItem struct {
+	ItemID     string `bson:"itemId" json:"itemId"`
+	TemplateID string `bson:"templateId" json:"templateId"`
+	ProfileID  string `bson:"profileId" json:"profileId"`
+}
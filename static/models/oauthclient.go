@@ -0,0 +1,30 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OAuthClient is a registered client allowed to call the token endpoints.
+// Lifetimes are in hours; zero falls back to the backend defaults.
+type OAuthClient struct {
+	ID                primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClientID          string             `bson:"clientId" json:"clientId"`
+	SecretHash        string             `bson:"secretHash" json:"-"`
+	GrantTypes        []string           `bson:"grantTypes" json:"grantTypes"`
+	Scopes            []string           `bson:"scopes" json:"scopes"`
+	AccessTokenHours  int                `bson:"accessTokenHours" json:"accessTokenHours"`
+	RefreshTokenHours int                `bson:"refreshTokenHours" json:"refreshTokenHours"`
+}
+
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	// otp only finishes a password login that asked for 2FA.
+	if grantType == "otp" {
+		grantType = "password"
+	}
+	for _, g := range c.GrantTypes {
+		if g == grantType {
+			return true
+		}
+	}
+	return false
+}
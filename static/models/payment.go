@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type PaymentStatus string
+
+const (
+	PaymentPending   PaymentStatus = "pending"
+	PaymentCompleted PaymentStatus = "completed"
+	PaymentFailed    PaymentStatus = "failed"
+)
+
+// Payment is a real-money order for a RealMoney offer. It is placed by
+// PurchaseCatalogEntry and settled by the payment provider's callback;
+// MtxQuantity V-Bucks are credited once it completes. Quantity is how many
+// times the order counts against the offer's purchase limits.
+type Payment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	OrderID     string             `bson:"orderId" json:"orderId"`
+	AccountID   string             `bson:"accountId" json:"accountId"`
+	OfferID     string             `bson:"offerId" json:"offerId"`
+	AppStoreID  string             `bson:"appStoreId" json:"appStoreId"`
+	MtxQuantity int                `bson:"mtxQuantity" json:"mtxQuantity"`
+	Quantity    int                `bson:"quantity" json:"quantity"`
+	Status      PaymentStatus      `bson:"status" json:"status"`
+	Created     time.Time          `bson:"created" json:"created"`
+	Settled     time.Time          `bson:"settled,omitempty" json:"settled,omitempty"`
+}
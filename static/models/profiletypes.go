@@ -0,0 +1,617 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// Typed views of the MCP profiles stored in Profiles.Profiles. Every struct
+// keeps the keys it was decoded from, so data it has no field for survives a
+// round trip and untouched fields are written back exactly as stored.
+
+type ProfileBase struct {
+	Created         string       `json:"created"`
+	Updated         string       `json:"updated"`
+	Rvn             int          `json:"rvn"`
+	WipeNumber      int          `json:"wipeNumber"`
+	AccountID       string       `json:"accountId"`
+	ProfileID       string       `json:"profileId"`
+	Version         string       `json:"version"`
+	Items           ProfileItems `json:"-"`
+	CommandRevision int          `json:"commandRevision"`
+}
+
+// Profile is any profile whose stats are not modelled.
+type Profile struct {
+	ProfileBase
+	Stats ProfileStats `json:"stats"`
+
+	rawFields
+}
+
+type ProfileStats struct {
+	Attributes map[string]interface{} `json:"attributes"`
+
+	rawFields
+}
+
+type AthenaProfile struct {
+	ProfileBase
+	Stats AthenaStats `json:"stats"`
+
+	rawFields
+}
+
+type AthenaStats struct {
+	Attributes AthenaAttributes `json:"attributes"`
+
+	rawFields
+}
+
+type AthenaAttributes struct {
+	SeasonNum               int          `json:"season_num"`
+	Level                   int          `json:"level"`
+	XP                      float64      `json:"xp"`
+	AccountLevel            int          `json:"accountLevel"`
+	BookLevel               int          `json:"book_level"`
+	BookXP                  int          `json:"book_xp"`
+	BookPurchased           bool         `json:"book_purchased"`
+	SeasonMatchBoost        float64      `json:"season_match_boost"`
+	SeasonFriendMatchBoost  float64      `json:"season_friend_match_boost"`
+	Loadouts                []string     `json:"loadouts"`
+	LastAppliedLoadout      string       `json:"last_applied_loadout"`
+	ActiveLoadoutIndex      int          `json:"active_loadout_index"`
+	BannerIcon              string       `json:"banner_icon"`
+	BannerColor             string       `json:"banner_color"`
+	FavoriteCharacter       string       `json:"favorite_character"`
+	FavoriteBackpack        string       `json:"favorite_backpack"`
+	FavoritePickaxe         string       `json:"favorite_pickaxe"`
+	FavoriteGlider          string       `json:"favorite_glider"`
+	FavoriteSkyDiveContrail string       `json:"favorite_skydivecontrail"`
+	FavoriteLoadingScreen   string       `json:"favorite_loadingscreen"`
+	FavoriteMusicPack       string       `json:"favorite_musicpack"`
+	FavoriteDance           []string     `json:"favorite_dance"`
+	FavoriteItemWraps       []string     `json:"favorite_itemwraps"`
+	QuestManager            QuestManager `json:"quest_manager"`
+	LifetimeWins            int          `json:"lifetime_wins"`
+	LifetimeEliminations    int          `json:"lifetime_eliminations"`
+	MatchesPlayed           int          `json:"matches_played"`
+
+	rawFields
+}
+
+// QuestManager tracks when daily quests were last handed out and how many
+// rerolls are left today.
+type QuestManager struct {
+	DailyLoginInterval string `json:"dailyLoginInterval"`
+	DailyQuestRerolls  int    `json:"dailyQuestRerolls"`
+
+	rawFields
+}
+
+type CommonCoreProfile struct {
+	ProfileBase
+	Stats CommonCoreStats `json:"stats"`
+
+	rawFields
+}
+
+type CommonCoreStats struct {
+	Attributes CommonCoreAttributes `json:"attributes"`
+
+	rawFields
+}
+
+type CommonCoreAttributes struct {
+	CurrentMtxPlatform    string `json:"current_mtx_platform"`
+	MtxAffiliate          string `json:"mtx_affiliate"`
+	MtxAffiliateSetTime   string `json:"mtx_affiliate_set_time"`
+	AllowedToSendGifts    bool   `json:"allowed_to_send_gifts"`
+	AllowedToReceiveGifts bool   `json:"allowed_to_receive_gifts"`
+	MfaEnabled            bool   `json:"mfa_enabled"`
+	InventoryLimitBonus   int    `json:"inventory_limit_bonus"`
+
+	MtxPurchaseHistory MtxPurchaseHistory `json:"mtx_purchase_history"`
+	DailyRewards       DailyRewards       `json:"daily_rewards"`
+
+	rawFields
+}
+
+// MtxPurchaseHistory lists the V-Bucks purchases a player can refund and the
+// refund tickets they have used.
+type MtxPurchaseHistory struct {
+	RefundsUsed   int            `json:"refundsUsed"`
+	RefundCredits int            `json:"refundCredits"`
+	Purchases     []*MtxPurchase `json:"purchases"`
+
+	rawFields
+}
+
+type MtxPurchase struct {
+	PurchaseID         string                 `json:"purchaseId"`
+	OfferID            string                 `json:"offerId"`
+	PurchaseDate       string                 `json:"purchaseDate"`
+	RefundDate         string                 `json:"refundDate"`
+	FreeRefundEligible bool                   `json:"freeRefundEligible"`
+	Fulfillments       []interface{}          `json:"fulfillments"`
+	LootResult         []LootResult           `json:"lootResult"`
+	TotalMtxPaid       int                    `json:"totalMtxPaid"`
+	Quantity           int                    `json:"quantity"`
+	Metadata           map[string]interface{} `json:"metadata"`
+	GameContext        string                 `json:"gameContext"`
+
+	rawFields
+}
+
+// LootResult is one item a purchase or gift box handed out.
+type LootResult struct {
+	ItemType    string `json:"itemType"`
+	ItemGuid    string `json:"itemGuid"`
+	ItemProfile string `json:"itemProfile,omitempty"`
+	Quantity    int    `json:"quantity"`
+}
+
+// DailyRewards tracks the login reward calendar.
+type DailyRewards struct {
+	TotalDaysLoggedIn int    `json:"totalDaysLoggedIn"`
+	NextDefaultReward int    `json:"nextDefaultReward"`
+	LastClaimDate     string `json:"lastClaimDate"`
+
+	rawFields
+}
+
+// CampaignProfile is the Save the World campaign profile.
+type CampaignProfile struct {
+	ProfileBase
+	Stats CampaignStats `json:"stats"`
+
+	rawFields
+}
+
+type CampaignStats struct {
+	Attributes CampaignAttributes `json:"attributes"`
+
+	rawFields
+}
+
+type CampaignAttributes struct {
+	MissionAlertRedemptionRecord MissionAlertRedemptionRecord `json:"mission_alert_redemption_record"`
+	CollectionBook               CollectionBook               `json:"collection_book"`
+
+	rawFields
+}
+
+// MissionAlertRedemptionRecord lists the mission alerts claimed in the
+// current rotation.
+type MissionAlertRedemptionRecord struct {
+	ClaimData []MissionAlertClaim `json:"claimData"`
+
+	rawFields
+}
+
+type MissionAlertClaim struct {
+	MissionAlertID         string `json:"missionAlertId"`
+	RedemptionDateUtc      string `json:"redemptionDateUtc"`
+	EvictClaimDataAfterUtc string `json:"evictClaimDataAfterUtc"`
+}
+
+type CollectionBook struct {
+	BookXP                 int `json:"book_xp"`
+	MaxBookXPLevelAchieved int `json:"maxBookXpLevelAchieved"`
+
+	rawFields
+}
+
+type ProfileItem struct {
+	TemplateID string         `json:"templateId"`
+	Attributes ItemAttributes `json:"attributes"`
+	Quantity   int            `json:"quantity"`
+
+	rawFields
+}
+
+type ItemAttributes struct {
+	ItemSeen        bool             `json:"item_seen"`
+	Favorite        bool             `json:"favorite"`
+	Level           int              `json:"level"`
+	MaxLevelBonus   int              `json:"max_level_bonus"`
+	XP              int              `json:"xp"`
+	Variants        []ItemVariant    `json:"variants"`
+	LockerName      string           `json:"locker_name"`
+	LockerSlotsData *LockerSlotsData `json:"locker_slots_data"`
+	BannerIcon      string           `json:"banner_icon_template"`
+	BannerColor     string           `json:"banner_color_template"`
+	QuestState      string           `json:"quest_state"`
+	CreationTime    string           `json:"creation_time"`
+	LastStateChange string           `json:"last_state_change_time"`
+	Platform        string           `json:"platform"`
+	FromAccountID   string           `json:"fromAccountId"`
+	LootList        []LootResult     `json:"lootList"`
+	GiftedOn        string           `json:"giftedOn"`
+
+	rawFields
+}
+
+type ItemVariant struct {
+	Channel string   `json:"channel"`
+	Active  string   `json:"active"`
+	Owned   []string `json:"owned"`
+}
+
+type LockerSlotsData struct {
+	Slots map[string]*LockerSlot `json:"slots"`
+}
+
+type LockerSlot struct {
+	Items          []string          `json:"items"`
+	ActiveVariants []*LockerVariants `json:"activeVariants"`
+}
+
+type LockerVariants struct {
+	Variants []ItemVariant `json:"variants"`
+}
+
+// NewProfileItem returns an unseen item with quantity 1.
+func NewProfileItem(templateID string) *ProfileItem {
+	return &ProfileItem{TemplateID: templateID, Quantity: 1}
+}
+
+// Clone returns a deep copy of the item.
+func (i *ProfileItem) Clone() (*ProfileItem, error) {
+	clone := &ProfileItem{}
+	if err := convert(i, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// Base gives access to the fields every profile type shares.
+func (b *ProfileBase) Base() *ProfileBase {
+	return b
+}
+
+// ProfileItems holds a profile's items. An item is only decoded when it is
+// first read, so profiles with thousands of cosmetics stay cheap to open.
+type ProfileItems struct {
+	raw   map[string]interface{}
+	typed map[string]*ProfileItem
+}
+
+// Get returns the item, or false if it doesn't exist or can't be decoded.
+func (p *ProfileItems) Get(id string) (*ProfileItem, bool) {
+	if item, ok := p.typed[id]; ok {
+		return item, item != nil
+	}
+	raw, ok := p.raw[id]
+	if !ok {
+		return nil, false
+	}
+	item := &ProfileItem{}
+	if err := convert(raw, item); err != nil {
+		return nil, false
+	}
+	p.set(id, item)
+	return item, true
+}
+
+// Set adds or replaces an item.
+func (p *ProfileItems) Set(id string, item *ProfileItem) {
+	p.set(id, item)
+}
+
+// Delete removes an item.
+func (p *ProfileItems) Delete(id string) {
+	p.set(id, nil)
+}
+
+func (p *ProfileItems) set(id string, item *ProfileItem) {
+	if p.typed == nil {
+		p.typed = map[string]*ProfileItem{}
+	}
+	p.typed[id] = item
+}
+
+// Has reports whether the item exists.
+func (p *ProfileItems) Has(id string) bool {
+	if item, ok := p.typed[id]; ok {
+		return item != nil
+	}
+	_, ok := p.raw[id]
+	return ok
+}
+
+// TemplateID reads an item's template without decoding the item.
+func (p *ProfileItems) TemplateID(id string) string {
+	if item, ok := p.typed[id]; ok {
+		if item == nil {
+			return ""
+		}
+		return item.TemplateID
+	}
+	raw, _ := p.raw[id].(map[string]interface{})
+	templateID, _ := raw["templateId"].(string)
+	return templateID
+}
+
+// IDs lists the item ids in a stable order.
+func (p *ProfileItems) IDs() []string {
+	ids := make([]string, 0, len(p.raw)+len(p.typed))
+	for id := range p.raw {
+		if item, ok := p.typed[id]; !ok || item != nil {
+			ids = append(ids, id)
+		}
+	}
+	for id, item := range p.typed {
+		if _, ok := p.raw[id]; !ok && item != nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// export returns the stored form: untouched items as they were read, changed
+// ones encoded again.
+func (p *ProfileItems) export() (map[string]interface{}, error) {
+	out := make(map[string]interface{}, len(p.raw)+len(p.typed))
+	for id, raw := range p.raw {
+		out[id] = raw
+	}
+	for id, item := range p.typed {
+		if item == nil {
+			delete(out, id)
+			continue
+		}
+		var encoded map[string]interface{}
+		if err := convert(item, &encoded); err != nil {
+			return nil, err
+		}
+		out[id] = encoded
+	}
+	return out, nil
+}
+
+// DecodeProfile fills v, one of the typed profiles, from a stored profile.
+func DecodeProfile(raw interface{}, v interface{}) error {
+	stored, ok := raw.(map[string]interface{})
+	if !ok {
+		if err := convert(raw, &stored); err != nil {
+			return err
+		}
+	}
+
+	rest := make(map[string]interface{}, len(stored))
+	for key, value := range stored {
+		if key != "items" {
+			rest[key] = value
+		}
+	}
+	if err := convert(rest, v); err != nil {
+		return err
+	}
+
+	items, ok := stored["items"].(map[string]interface{})
+	if !ok && stored["items"] != nil {
+		if err := convert(stored["items"], &items); err != nil {
+			return err
+		}
+	}
+	if base, ok := v.(interface{ Base() *ProfileBase }); ok {
+		base.Base().Items = ProfileItems{raw: items}
+	}
+	return nil
+}
+
+// EncodeProfile turns a typed profile back into the map form it is stored in.
+func EncodeProfile(v interface{}) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	if err := convert(v, &out); err != nil {
+		return nil, err
+	}
+	if base, ok := v.(interface{ Base() *ProfileBase }); ok {
+		items, err := base.Base().Items.export()
+		if err != nil {
+			return nil, err
+		}
+		out["items"] = items
+	}
+	return out, nil
+}
+
+func convert(from, to interface{}) error {
+	data, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, to)
+}
+
+func (p *Profile) UnmarshalJSON(data []byte) error {
+	type plain Profile
+	return p.rawFields.decode(data, (*plain)(p))
+}
+
+func (p Profile) MarshalJSON() ([]byte, error) {
+	type plain Profile
+	return p.rawFields.encode(plain(p))
+}
+
+func (s *ProfileStats) UnmarshalJSON(data []byte) error {
+	type plain ProfileStats
+	return s.rawFields.decode(data, (*plain)(s))
+}
+
+func (s ProfileStats) MarshalJSON() ([]byte, error) {
+	type plain ProfileStats
+	return s.rawFields.encode(plain(s))
+}
+
+func (p *AthenaProfile) UnmarshalJSON(data []byte) error {
+	type plain AthenaProfile
+	return p.rawFields.decode(data, (*plain)(p))
+}
+
+func (p AthenaProfile) MarshalJSON() ([]byte, error) {
+	type plain AthenaProfile
+	return p.rawFields.encode(plain(p))
+}
+
+func (s *AthenaStats) UnmarshalJSON(data []byte) error {
+	type plain AthenaStats
+	return s.rawFields.decode(data, (*plain)(s))
+}
+
+func (s AthenaStats) MarshalJSON() ([]byte, error) {
+	type plain AthenaStats
+	return s.rawFields.encode(plain(s))
+}
+
+func (a *AthenaAttributes) UnmarshalJSON(data []byte) error {
+	type plain AthenaAttributes
+	return a.rawFields.decode(data, (*plain)(a))
+}
+
+func (a AthenaAttributes) MarshalJSON() ([]byte, error) {
+	type plain AthenaAttributes
+	return a.rawFields.encode(plain(a))
+}
+
+func (q *QuestManager) UnmarshalJSON(data []byte) error {
+	type plain QuestManager
+	return q.rawFields.decode(data, (*plain)(q))
+}
+
+func (q QuestManager) MarshalJSON() ([]byte, error) {
+	type plain QuestManager
+	return q.rawFields.encode(plain(q))
+}
+
+func (p *CommonCoreProfile) UnmarshalJSON(data []byte) error {
+	type plain CommonCoreProfile
+	return p.rawFields.decode(data, (*plain)(p))
+}
+
+func (p CommonCoreProfile) MarshalJSON() ([]byte, error) {
+	type plain CommonCoreProfile
+	return p.rawFields.encode(plain(p))
+}
+
+func (s *CommonCoreStats) UnmarshalJSON(data []byte) error {
+	type plain CommonCoreStats
+	return s.rawFields.decode(data, (*plain)(s))
+}
+
+func (s CommonCoreStats) MarshalJSON() ([]byte, error) {
+	type plain CommonCoreStats
+	return s.rawFields.encode(plain(s))
+}
+
+func (a *CommonCoreAttributes) UnmarshalJSON(data []byte) error {
+	type plain CommonCoreAttributes
+	return a.rawFields.decode(data, (*plain)(a))
+}
+
+func (a CommonCoreAttributes) MarshalJSON() ([]byte, error) {
+	type plain CommonCoreAttributes
+	return a.rawFields.encode(plain(a))
+}
+
+func (i *ProfileItem) UnmarshalJSON(data []byte) error {
+	type plain ProfileItem
+	return i.rawFields.decode(data, (*plain)(i))
+}
+
+func (i ProfileItem) MarshalJSON() ([]byte, error) {
+	type plain ProfileItem
+	return i.rawFields.encode(plain(i))
+}
+
+func (a *ItemAttributes) UnmarshalJSON(data []byte) error {
+	type plain ItemAttributes
+	return a.rawFields.decode(data, (*plain)(a))
+}
+
+func (a ItemAttributes) MarshalJSON() ([]byte, error) {
+	type plain ItemAttributes
+	return a.rawFields.encode(plain(a))
+}
+
+func (h *MtxPurchaseHistory) UnmarshalJSON(data []byte) error {
+	type plain MtxPurchaseHistory
+	return h.rawFields.decode(data, (*plain)(h))
+}
+
+func (h MtxPurchaseHistory) MarshalJSON() ([]byte, error) {
+	type plain MtxPurchaseHistory
+	return h.rawFields.encode(plain(h))
+}
+
+func (p *MtxPurchase) UnmarshalJSON(data []byte) error {
+	type plain MtxPurchase
+	return p.rawFields.decode(data, (*plain)(p))
+}
+
+func (p MtxPurchase) MarshalJSON() ([]byte, error) {
+	type plain MtxPurchase
+	return p.rawFields.encode(plain(p))
+}
+
+func (d *DailyRewards) UnmarshalJSON(data []byte) error {
+	type plain DailyRewards
+	return d.rawFields.decode(data, (*plain)(d))
+}
+
+func (d DailyRewards) MarshalJSON() ([]byte, error) {
+	type plain DailyRewards
+	return d.rawFields.encode(plain(d))
+}
+
+func (p *CampaignProfile) UnmarshalJSON(data []byte) error {
+	type plain CampaignProfile
+	return p.rawFields.decode(data, (*plain)(p))
+}
+
+func (p CampaignProfile) MarshalJSON() ([]byte, error) {
+	type plain CampaignProfile
+	return p.rawFields.encode(plain(p))
+}
+
+func (s *CampaignStats) UnmarshalJSON(data []byte) error {
+	type plain CampaignStats
+	return s.rawFields.decode(data, (*plain)(s))
+}
+
+func (s CampaignStats) MarshalJSON() ([]byte, error) {
+	type plain CampaignStats
+	return s.rawFields.encode(plain(s))
+}
+
+func (a *CampaignAttributes) UnmarshalJSON(data []byte) error {
+	type plain CampaignAttributes
+	return a.rawFields.decode(data, (*plain)(a))
+}
+
+func (a CampaignAttributes) MarshalJSON() ([]byte, error) {
+	type plain CampaignAttributes
+	return a.rawFields.encode(plain(a))
+}
+
+func (r *MissionAlertRedemptionRecord) UnmarshalJSON(data []byte) error {
+	type plain MissionAlertRedemptionRecord
+	return r.rawFields.decode(data, (*plain)(r))
+}
+
+func (r MissionAlertRedemptionRecord) MarshalJSON() ([]byte, error) {
+	type plain MissionAlertRedemptionRecord
+	return r.rawFields.encode(plain(r))
+}
+
+func (b *CollectionBook) UnmarshalJSON(data []byte) error {
+	type plain CollectionBook
+	return b.rawFields.decode(data, (*plain)(b))
+}
+
+func (b CollectionBook) MarshalJSON() ([]byte, error) {
+	type plain CollectionBook
+	return b.rawFields.encode(plain(b))
+}
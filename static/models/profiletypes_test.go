@@ -0,0 +1,115 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func storedAthena() map[string]interface{} {
+	return map[string]interface{}{
+		"created":         "2024-01-01T00:00:00Z",
+		"rvn":             float64(4),
+		"commandRevision": float64(3),
+		"profileId":       "athena",
+		"someFutureKey":   map[string]interface{}{"kept": true},
+		"stats": map[string]interface{}{
+			"attributes": map[string]interface{}{
+				"level":          float64(12),
+				"book_purchased": float64(1),
+				"xp":             float64(1500),
+				"unknown_stat":   "left alone",
+			},
+		},
+		"items": map[string]interface{}{
+			"item1": map[string]interface{}{
+				"templateId": "AthenaCharacter:cid_001",
+				"attributes": map[string]interface{}{"item_seen": false, "custom": "kept"},
+				"quantity":   float64(1),
+			},
+			"item2": map[string]interface{}{
+				"templateId": "AthenaPickaxe:pickaxe_001",
+				"quantity":   float64(1),
+			},
+		},
+	}
+}
+
+func TestProfileRoundTripKeepsUnknownFields(t *testing.T) {
+	var athena AthenaProfile
+	if err := DecodeProfile(storedAthena(), &athena); err != nil {
+		t.Fatalf("DecodeProfile: %v", err)
+	}
+
+	out, err := EncodeProfile(&athena)
+	if err != nil {
+		t.Fatalf("EncodeProfile: %v", err)
+	}
+	if !reflect.DeepEqual(out, storedAthena()) {
+		t.Fatalf("unchanged profile did not round trip:\n got %#v\nwant %#v", out, storedAthena())
+	}
+}
+
+func TestProfileDecodeCoercesLooseTypes(t *testing.T) {
+	var athena AthenaProfile
+	if err := DecodeProfile(storedAthena(), &athena); err != nil {
+		t.Fatalf("DecodeProfile: %v", err)
+	}
+	attrs := athena.Stats.Attributes
+	if !attrs.BookPurchased {
+		t.Errorf("book_purchased stored as 1 decoded as false")
+	}
+	if attrs.Level != 12 || athena.Rvn != 4 || athena.CommandRevision != 3 {
+		t.Errorf("got level %d rvn %d commandRevision %d", attrs.Level, athena.Rvn, athena.CommandRevision)
+	}
+	if got := attrs.Extra("unknown_stat"); got != "left alone" {
+		t.Errorf("Extra(unknown_stat) = %v", got)
+	}
+}
+
+func TestProfileEncodeWritesChanges(t *testing.T) {
+	var athena AthenaProfile
+	if err := DecodeProfile(storedAthena(), &athena); err != nil {
+		t.Fatalf("DecodeProfile: %v", err)
+	}
+
+	athena.Stats.Attributes.Level = 13
+	athena.Stats.Attributes.FavoriteCharacter = "item1"
+	item, ok := athena.Items.Get("item1")
+	if !ok {
+		t.Fatalf("item1 not found")
+	}
+	item.Attributes.Favorite = true
+	athena.Items.Delete("item2")
+	athena.Items.Set("item3", NewProfileItem("AthenaGlider:glider_001"))
+
+	out, err := EncodeProfile(&athena)
+	if err != nil {
+		t.Fatalf("EncodeProfile: %v", err)
+	}
+
+	attrs := out["stats"].(map[string]interface{})["attributes"].(map[string]interface{})
+	if attrs["level"] != float64(13) || attrs["favorite_character"] != "item1" {
+		t.Errorf("changed attributes not written: %#v", attrs)
+	}
+	if attrs["unknown_stat"] != "left alone" {
+		t.Errorf("unknown attribute lost: %#v", attrs)
+	}
+	if _, ok := attrs["favorite_backpack"]; ok {
+		t.Errorf("unset field was written: %#v", attrs)
+	}
+	if out["someFutureKey"] == nil {
+		t.Errorf("unknown top level key lost")
+	}
+
+	items := out["items"].(map[string]interface{})
+	if _, ok := items["item2"]; ok {
+		t.Errorf("deleted item still stored")
+	}
+	item1 := items["item1"].(map[string]interface{})["attributes"].(map[string]interface{})
+	if item1["favorite"] != true || item1["custom"] != "kept" {
+		t.Errorf("item1 attributes = %#v", item1)
+	}
+	if item3, ok := items["item3"].(map[string]interface{}); !ok || item3["templateId"] != "AthenaGlider:glider_001" {
+		t.Errorf("added item = %#v", items["item3"])
+	}
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// rawFields remembers what a typed struct was decoded from, so keys it has no
+// field for survive and unchanged fields are written back exactly as stored.
+type rawFields struct {
+	raw  map[string]json.RawMessage
+	seen map[string]string
+}
+
+// Extra returns a stored key the struct has no field for.
+func (r *rawFields) Extra(key string) interface{} {
+	var value interface{}
+	if data, ok := r.raw[key]; ok {
+		_ = json.Unmarshal(data, &value)
+	}
+	return value
+}
+
+// SetExtra stores a key the struct has no field for.
+func (r *rawFields) SetExtra(key string, value interface{}) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	if r.raw == nil {
+		r.raw = map[string]json.RawMessage{}
+	}
+	r.raw[key] = data
+}
+
+// decode fills v, the plain version of the struct embedding r. Known fields
+// stored with a loose type, e.g. 0/1 for a bool or a fractional int, are
+// coerced instead of failing the whole profile.
+func (r *rawFields) decode(data []byte, v interface{}) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		var loose map[string]interface{}
+		if err := json.Unmarshal(data, &loose); err != nil {
+			return err
+		}
+		coerceFields(reflect.TypeOf(v).Elem(), loose)
+		if err := convert(loose, v); err != nil {
+			return err
+		}
+	}
+
+	r.raw = raw
+	r.seen = map[string]string{}
+	eachField(reflect.ValueOf(v).Elem(), func(name string, value reflect.Value) {
+		if _, had := raw[name]; had && isLeaf(value.Type()) {
+			r.seen[name] = fingerprint(value)
+		}
+	})
+	return nil
+}
+
+// encode writes the stored keys with the fields that changed since decoding
+// laid over them. A field that wasn't stored is only written once non-zero.
+func (r *rawFields) encode(v interface{}) ([]byte, error) {
+	out := make(map[string]interface{}, len(r.raw))
+	for key, value := range r.raw {
+		out[key] = value
+	}
+	eachField(reflect.ValueOf(v), func(name string, value reflect.Value) {
+		if _, had := r.raw[name]; had {
+			if !isLeaf(value.Type()) || fingerprint(value) != r.seen[name] {
+				out[name] = value.Interface()
+			}
+		} else if !value.IsZero() {
+			out[name] = value.Interface()
+		}
+	})
+	return json.Marshal(out)
+}
+
+// eachField calls fn for every JSON field of v, including embedded ones.
+func eachField(v reflect.Value, fn func(name string, value reflect.Value)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			eachField(v.Field(i), fn)
+			continue
+		}
+		if name := jsonName(field); name != "" {
+			fn(name, v.Field(i))
+		}
+	}
+}
+
+// isLeaf reports whether a field is compared as a whole. Structs and maps hold
+// values that keep their own raw keys, so they are always encoded again.
+func isLeaf(t reflect.Type) bool {
+	return t.Kind() != reflect.Struct && t.Kind() != reflect.Map
+}
+
+func fingerprint(value reflect.Value) string {
+	data, _ := json.Marshal(value.Interface())
+	return string(data)
+}
+
+func jsonName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name
+}
+
+// coerceFields rewrites loosely typed values in m so they decode into t.
+// Values that can't be made to fit are dropped and keep their zero value.
+func coerceFields(t reflect.Type, m map[string]interface{}) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.IsExported() && field.Type.Kind() == reflect.Struct {
+			coerceFields(field.Type, m)
+			continue
+		}
+		name := jsonName(field)
+		value, ok := m[name]
+		if name == "" || !ok || value == nil {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Bool:
+			if n, ok := value.(float64); ok {
+				m[name] = n != 0
+			} else if _, ok := value.(bool); !ok {
+				delete(m, name)
+			}
+		case reflect.Int:
+			switch n := value.(type) {
+			case float64:
+				m[name] = int(n)
+			case bool:
+				if n {
+					m[name] = 1
+				} else {
+					m[name] = 0
+				}
+			default:
+				delete(m, name)
+			}
+		case reflect.Float64:
+			if _, ok := value.(float64); !ok {
+				delete(m, name)
+			}
+		case reflect.String:
+			if _, ok := value.(string); !ok {
+				delete(m, name)
+			}
+		case reflect.Slice:
+			if _, ok := value.([]interface{}); !ok {
+				delete(m, name)
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ReceiptType string
+
+const (
+	ReceiptPurchase     ReceiptType = "purchase"
+	ReceiptBattlePass   ReceiptType = "battlepass"
+	ReceiptGiftSent     ReceiptType = "gift_sent"
+	ReceiptGiftReceived ReceiptType = "gift_received"
+	ReceiptGiftReversal ReceiptType = "gift_reversal"
+	ReceiptRefund       ReceiptType = "refund"
+	ReceiptGrant        ReceiptType = "grant"
+	ReceiptRealMoney    ReceiptType = "real_money"
+)
+
+// Receipt records one change to what an account owns or holds in V-Bucks.
+// Amount is the V-Bucks the account gained, negative when it spent them.
+// Items were granted, or taken back for refunds and reversals.
+// ReceiptID is stable; for shop purchases it is the mtx_purchase_history
+// purchaseId, so a refund's RelatedReceiptID points at the purchase.
+// Quantity is how many times the offer was bought, counted against its
+// purchase limits.
+type Receipt struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	ReceiptID        string             `bson:"receiptId" json:"receiptId"`
+	AccountID        string             `bson:"accountId" json:"accountId"`
+	Type             ReceiptType        `bson:"type" json:"type"`
+	OfferID          string             `bson:"offerId,omitempty" json:"offerId,omitempty"`
+	CurrencyType     string             `bson:"currencyType,omitempty" json:"currencyType,omitempty"`
+	CurrencySubType  string             `bson:"currencySubType,omitempty" json:"currencySubType,omitempty"`
+	Amount           int                `bson:"amount" json:"amount"`
+	Quantity         int                `bson:"quantity,omitempty" json:"quantity,omitempty"`
+	Items            []ReceiptItem      `bson:"items,omitempty" json:"items,omitempty"`
+	RelatedAccountID string             `bson:"relatedAccountId,omitempty" json:"relatedAccountId,omitempty"`
+	RelatedReceiptID string             `bson:"relatedReceiptId,omitempty" json:"relatedReceiptId,omitempty"`
+	Note             string             `bson:"note,omitempty" json:"note,omitempty"`
+	Created          time.Time          `bson:"created" json:"created"`
+}
+
+type ReceiptItem struct {
+	TemplateID string `bson:"templateId" json:"templateId"`
+	ItemID     string `bson:"itemId,omitempty" json:"itemId,omitempty"`
+	Quantity   int    `bson:"quantity" json:"quantity"`
+}
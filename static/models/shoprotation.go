@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ShopSourceRotation  = "rotation"
+	ShopSourceScheduled = "scheduled"
+)
+
+// ShopRotation is the item shop that runs from Date until the start of
+// Until, both UTC days formatted YYYY-MM-DD. Rotations last one day;
+// scheduled shops may run longer.
+type ShopRotation struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Date        string             `bson:"date" json:"date"`
+	Until       string             `bson:"until" json:"until"`
+	Source      string             `bson:"source" json:"source"`
+	ScheduledBy string             `bson:"scheduledBy,omitempty" json:"scheduledBy,omitempty"`
+	Daily       []ShopRotationItem `bson:"daily" json:"daily"`
+	Featured    []ShopRotationItem `bson:"featured" json:"featured"`
+	Created     time.Time          `bson:"created" json:"created"`
+}
+
+// ShopRotationItem is one offer of a shop. Limits of 0 mean unlimited.
+// Prices are in V-Bucks unless CurrencyType is GameItem, which charges the
+// CurrencySubType item, e.g. event tokens.
+type ShopRotationItem struct {
+	TemplateID      string `bson:"templateId" json:"templateId"`
+	Rarity          string `bson:"rarity" json:"rarity"`
+	Price           int    `bson:"price" json:"price"`
+	CurrencyType    string `bson:"currencyType,omitempty" json:"currencyType,omitempty"`
+	CurrencySubType string `bson:"currencySubType,omitempty" json:"currencySubType,omitempty"`
+	DailyLimit      int    `bson:"dailyLimit,omitempty" json:"dailyLimit,omitempty"`
+	WeeklyLimit     int    `bson:"weeklyLimit,omitempty" json:"weeklyLimit,omitempty"`
+	MonthlyLimit    int    `bson:"monthlyLimit,omitempty" json:"monthlyLimit,omitempty"`
+}
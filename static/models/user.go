@@ -7,15 +7,49 @@ import (
 )
 
 type User struct {
-	ID            primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Created       time.Time          `bson:"created" json:"created"`
-	Banned        bool               `bson:"banned" json:"banned"`
-	DiscordID     *string            `bson:"discordId,omitempty" json:"discordId,omitempty"`
-	AccountID     string             `bson:"accountId" json:"accountId"`
-	Username      string             `bson:"username" json:"username"`
-	Email         string             `bson:"email" json:"email"`
-	Password      string             `bson:"password" json:"password"`
-	MatchmakingID string             `bson:"matchmakingId" json:"matchmakingId"`
-	IsServer      bool               `bson:"isServer" json:"isServer"`
-	AcceptedEULA  bool               `bson:"acceptedEULA" json:"acceptedEULA"`
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Created        time.Time          `bson:"created" json:"created"`
+	Banned         bool               `bson:"banned" json:"banned"`
+	DiscordID      *string            `bson:"discordId,omitempty" json:"discordId,omitempty"`
+	AccountID      string             `bson:"accountId" json:"accountId"`
+	Username       string             `bson:"username" json:"username"`
+	Email          string             `bson:"email" json:"email"`
+	Password       string             `bson:"password" json:"password"`
+	MatchmakingID  string             `bson:"matchmakingId" json:"matchmakingId"`
+	IsServer       bool               `bson:"isServer" json:"isServer"`
+	AcceptedEULA   bool               `bson:"acceptedEULA" json:"acceptedEULA"`
+	DeviceAuths    []DeviceAuth       `bson:"deviceAuths,omitempty" json:"-"`
+	TwoFactor      TwoFactor          `bson:"twoFactor,omitempty" json:"-"`
+	LastIP         string             `bson:"lastIp,omitempty" json:"-"`
+	LastHWID       string             `bson:"lastHwid,omitempty" json:"-"`
+	NameHistory    []NameChange       `bson:"nameHistory,omitempty" json:"nameHistory,omitempty"`
+	LastNameChange time.Time          `bson:"lastNameChange,omitempty" json:"lastNameChange,omitempty"`
+}
+
+type NameChange struct {
+	OldName   string    `bson:"oldName" json:"oldName"`
+	NewName   string    `bson:"newName" json:"newName"`
+	ChangedBy string    `bson:"changedBy" json:"changedBy"`
+	ChangedAt time.Time `bson:"changedAt" json:"changedAt"`
+}
+
+// TwoFactor holds the TOTP settings of an account. PendingSecret is set
+// between enrolment and the first confirmed code.
+type TwoFactor struct {
+	Enabled       bool      `bson:"enabled" json:"enabled"`
+	Secret        string    `bson:"secret,omitempty" json:"-"`
+	PendingSecret string    `bson:"pendingSecret,omitempty" json:"-"`
+	RecoveryCodes []string  `bson:"recoveryCodes,omitempty" json:"-"`
+	EnabledAt     time.Time `bson:"enabledAt,omitempty" json:"enabledAt,omitempty"`
+}
+
+// DeviceAuth is a device credential that can log in through the device_auth
+// grant. Only the bcrypt hash of the secret is stored.
+type DeviceAuth struct {
+	DeviceID   string    `bson:"deviceId" json:"deviceId"`
+	SecretHash string    `bson:"secretHash" json:"-"`
+	UserAgent  string    `bson:"userAgent" json:"userAgent"`
+	CreatedIP  string    `bson:"createdIp" json:"createdIp"`
+	Created    time.Time `bson:"created" json:"created"`
+	LastAccess time.Time `bson:"lastAccess,omitempty" json:"lastAccess,omitempty"`
 }
@@ -0,0 +1,89 @@
+package tokens
+
+import (
+	log "VentureBackend/utils"
+	"strings"
+	"time"
+)
+
+const (
+	ExchangeCodeLifetime       = 5 * time.Minute
+	AuthorizationCodeLifetime  = 5 * time.Minute
+	TwoFactorChallengeLifetime = 5 * time.Minute
+)
+
+// CreateExchangeCode mints a one-time code that another client can redeem
+// with the exchange_code grant to log in as accountId.
+func CreateExchangeCode(accountId, clientId string) (string, time.Time, error) {
+	return createCode(KindExchangeCode, accountId, clientId, ExchangeCodeLifetime)
+}
+
+// CreateAuthorizationCode mints a one-time code that only clientId can redeem
+// with the authorization_code grant.
+func CreateAuthorizationCode(accountId, clientId string) (string, time.Time, error) {
+	return createCode(KindAuthorizationCode, accountId, clientId, AuthorizationCodeLifetime)
+}
+
+// CreateTwoFactorChallenge mints the challenge a password login returns when
+// the account has 2FA on. The otp grant redeems it together with a code.
+func CreateTwoFactorChallenge(accountId, clientId string) (string, time.Time, error) {
+	return createCode(KindTwoFactorChallenge, accountId, clientId, TwoFactorChallengeLifetime)
+}
+
+func ConsumeExchangeCode(code string) *StoredToken {
+	return consumeCode(KindExchangeCode, code)
+}
+
+func ConsumeAuthorizationCode(code string) *StoredToken {
+	return consumeCode(KindAuthorizationCode, code)
+}
+
+func ConsumeTwoFactorChallenge(challenge string) *StoredToken {
+	return consumeCode(KindTwoFactorChallenge, challenge)
+}
+
+func createCode(kind TokenKind, accountId, clientId string, lifetime time.Duration) (string, time.Time, error) {
+	repo, err := getRepository()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	now := time.Now()
+	expiry := now.Add(lifetime)
+	code := strings.ReplaceAll(MakeID(), "-", "")
+
+	err = repo.Save(ctx, StoredToken{
+		Kind:      kind,
+		Token:     code,
+		AccountID: accountId,
+		ClientID:  clientId,
+		CreatedAt: now,
+		ExpiresAt: expiry,
+	})
+	if err != nil {
+		log.Error.Logf("Failed to save %s: %v", kind, err)
+		return "", time.Time{}, err
+	}
+	return code, expiry, nil
+}
+
+func consumeCode(kind TokenKind, code string) *StoredToken {
+	if code == "" {
+		return nil
+	}
+	repo, err := getRepository()
+	if err != nil {
+		return nil
+	}
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	stored, err := repo.Consume(ctx, kind, code)
+	if err != nil {
+		log.Error.Logf("Failed to redeem %s: %v", kind, err)
+		return nil
+	}
+	return stored
+}
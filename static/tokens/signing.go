@@ -0,0 +1,116 @@
+package tokens
+
+import (
+	log "VentureBackend/utils"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultKeyID = "default"
+
+var (
+	signingKeys   = map[string][]byte{}
+	activeKeyID   = defaultKeyID
+	TokenIssuer   = "venturebackend"
+	TokenAudience = "fortnite"
+	ClockSkew     = 30 * time.Second
+)
+
+// loadSigningKeys reads the HMAC keys used for signing and verification.
+// JWT_SECRET is registered under the "default" kid, JWT_KEYS may hold extra
+// keys as a JSON object of kid -> secret so retired keys keep verifying
+// until their tokens expire, and JWT_ACTIVE_KID picks the key new tokens are
+// signed with.
+func loadSigningKeys() {
+	if len(JWTSecret) > 0 {
+		signingKeys[defaultKeyID] = JWTSecret
+	}
+
+	if raw := os.Getenv("JWT_KEYS"); raw != "" {
+		var keys map[string]string
+		if err := json.Unmarshal([]byte(raw), &keys); err != nil {
+			log.Error.Log("Invalid JWT_KEYS in env: ", err)
+		}
+		for kid, secret := range keys {
+			if kid == "" || secret == "" {
+				continue
+			}
+			signingKeys[kid] = []byte(secret)
+		}
+	}
+
+	if kid := os.Getenv("JWT_ACTIVE_KID"); kid != "" {
+		if _, ok := signingKeys[kid]; ok {
+			activeKeyID = kid
+		} else {
+			log.Error.Logf("JWT_ACTIVE_KID %s has no matching key, signing with %s", kid, activeKeyID)
+		}
+	}
+
+	if iss := os.Getenv("JWT_ISSUER"); iss != "" {
+		TokenIssuer = iss
+	}
+	if aud := os.Getenv("JWT_AUDIENCE"); aud != "" {
+		TokenAudience = aud
+	}
+	if skew := os.Getenv("JWT_CLOCK_SKEW_SECONDS"); skew != "" {
+		if seconds, err := strconv.Atoi(skew); err == nil && seconds >= 0 {
+			ClockSkew = time.Duration(seconds) * time.Second
+		}
+	}
+}
+
+func createToken(claims jwt.MapClaims) (string, error) {
+	key, ok := signingKeys[activeKeyID]
+	if !ok {
+		return "", errors.New("no active signing key configured")
+	}
+	claims["iss"] = TokenIssuer
+	claims["aud"] = TokenAudience
+	claims["iat"] = time.Now().Unix()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = activeKeyID
+	return token.SignedString(key)
+}
+
+func keyForToken(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("missing kid header")
+	}
+	key, ok := signingKeys[kid]
+	if !ok {
+		return nil, errors.New("unknown signing key " + kid)
+	}
+	return key, nil
+}
+
+// parseToken verifies the signature, issuer, audience and lifetime of a raw
+// JWT (without the eg1~ prefix) and returns its claims.
+func parseToken(tokenStr string) (jwt.MapClaims, error) {
+	parser := jwt.NewParser(
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(TokenIssuer),
+		jwt.WithAudience(TokenAudience),
+		jwt.WithLeeway(ClockSkew),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	)
+
+	token, err := parser.Parse(tokenStr, keyForToken)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("invalid JWT claims")
+	}
+	return claims, nil
+}
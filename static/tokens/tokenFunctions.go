@@ -2,11 +2,10 @@ package tokens
 
 import (
 	log "VentureBackend/utils"
+	"context"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
@@ -20,10 +19,8 @@ import (
 )
 
 var (
-	JWTSecret  []byte
-	tokensPath = "./static/tokens/tokens.json"
-	mu         sync.Mutex
-	tokenStore = TokenStore{}
+	JWTSecret []byte
+	mu        sync.Mutex
 )
 
 func init() {
@@ -36,37 +33,7 @@ func init() {
 		log.Backend.Log("JWT_SECRET not set in environment variables")
 	}
 	JWTSecret = []byte(secret)
-	err = loadTokens()
-	if err != nil {
-		log.Backend.Log("Failed to load tokens from file: ", err)
-	}
-}
-
-type ClientToken struct {
-	IP        string    `json:"ip"`
-	Token     string    `json:"token"`
-	CreatedAt time.Time `json:"createdAt"`
-	ExpiresAt time.Time `json:"expiresAt"`
-}
-
-type AccessToken struct {
-	AccountID string    `json:"accountId"`
-	Token     string    `json:"token"`
-	CreatedAt time.Time `json:"createdAt"`
-	ExpiresAt time.Time `json:"expiresAt"`
-}
-
-type RefreshToken struct {
-	AccountID string    `json:"accountId"`
-	Token     string    `json:"token"`
-	CreatedAt time.Time `json:"createdAt"`
-	ExpiresAt time.Time `json:"expiresAt"`
-}
-
-type TokenStore struct {
-	AccessTokens  []AccessToken  `json:"accessTokens"`
-	ClientTokens  []ClientToken  `json:"clientTokens"`
-	RefreshTokens []RefreshToken `json:"refreshTokens"`
+	loadSigningKeys()
 }
 
 type DecodedToken struct {
@@ -81,6 +48,7 @@ type DecodedToken struct {
 	DeviceID    string
 	DisplayName string
 	AccountID   string
+	Scopes      []string
 	RawClaims   jwt.MapClaims
 }
 
@@ -90,16 +58,11 @@ func DecodeJWT(tokenWithPrefix string) (*DecodedToken, error) {
 	}
 	tokenStr := strings.TrimPrefix(tokenWithPrefix, "eg1~")
 
-	token, _, err := new(jwt.Parser).ParseUnverified(tokenStr, jwt.MapClaims{})
+	claims, err := parseToken(tokenStr)
 	if err != nil {
 		return nil, err
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return nil, errors.New("invalid JWT claims")
-	}
-
 	sub, _ := claims["sub"].(string)
 	iss, _ := claims["iss"].(string)
 	aud, _ := claims["aud"].(string)
@@ -116,6 +79,15 @@ func DecodeJWT(tokenWithPrefix string) (*DecodedToken, error) {
 	}
 	exp := time.Unix(int64(expFloat), 0)
 
+	var scopes []string
+	if raw, ok := claims["scope"].([]interface{}); ok {
+		for _, s := range raw {
+			if scope, ok := s.(string); ok {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
 	return &DecodedToken{
 		ID:          jti,
 		Subject:     sub,
@@ -126,67 +98,13 @@ func DecodeJWT(tokenWithPrefix string) (*DecodedToken, error) {
 		TokenType:   t,
 		ClientID:    clid,
 		DeviceID:    dvid,
-		AccountID:   aud,
+		AccountID:   sub,
 		DisplayName: dn,
+		Scopes:      scopes,
 		RawClaims:   claims,
 	}, nil
 }
 
-func loadTokens() error {
-	mu.Lock()
-	defer mu.Unlock()
-
-	err := os.MkdirAll("./static/tokens", os.ModePerm)
-	if err != nil {
-		log.Error.Log("Failed to create tokens directory: ", err)
-		return errors.New("failed to create tokens directory")
-	}
-
-	if _, err := os.Stat(tokensPath); os.IsNotExist(err) {
-		empty := TokenStore{
-			AccessTokens:  []AccessToken{},
-			ClientTokens:  []ClientToken{},
-			RefreshTokens: []RefreshToken{},
-		}
-		data, _ := json.MarshalIndent(empty, "", "  ")
-		if err := ioutil.WriteFile(tokensPath, data, 0644); err != nil {
-			log.Error.Log("Failed to create empty tokens file: ", err)
-			return errors.New("failed to create empty tokens file")
-		}
-	}
-
-	data, err := ioutil.ReadFile(tokensPath)
-	if err != nil {
-		log.Error.Log("Failed to read tokens file: ", err)
-		return errors.New("failed to read tokens file")
-	}
-
-	err = json.Unmarshal(data, &tokenStore)
-	if err != nil {
-		log.Error.Log("Failed to unmarshal tokens: ", err)
-		return errors.New("failed to unmarshal tokens")
-	}
-
-	return nil
-}
-
-func saveTokens() error {
-	mu.Lock()
-	defer mu.Unlock()
-
-	data, err := json.MarshalIndent(tokenStore, "", "  ")
-	if err != nil {
-		log.Error.Log("Marshal error while saving tokens: ", err)
-		return err
-	}
-	err = ioutil.WriteFile(tokensPath, data, 0644)
-	if err != nil {
-		log.Error.Log("WriteFile error while saving tokens: ", err)
-		return err
-	}
-	return nil
-}
-
 func MakeID() string {
 	return uuid.New().String()
 }
@@ -195,12 +113,11 @@ func encodeBase64(input string) string {
 	return base64.StdEncoding.EncodeToString([]byte(input))
 }
 
-func createToken(claims jwt.MapClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(JWTSecret)
+func storeContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 5*time.Second)
 }
 
-func CreateClient(clientId, grantType, ip string, expiresIn int) (string, error) {
+func CreateClient(clientId, grantType, ip string, scopes []string, expiresIn int) (string, error) {
 	now := time.Now()
 	expiry := now.Add(time.Duration(expiresIn) * time.Hour)
 	claims := jwt.MapClaims{
@@ -211,6 +128,7 @@ func CreateClient(clientId, grantType, ip string, expiresIn int) (string, error)
 		"clid":          clientId,
 		"ic":            true,
 		"am":            grantType,
+		"scope":         scopes,
 		"jti":           strings.ReplaceAll(MakeID(), "-", ""),
 		"creation_date": now.Unix(),
 		"hours_expire":  expiresIn,
@@ -223,29 +141,32 @@ func CreateClient(clientId, grantType, ip string, expiresIn int) (string, error)
 	}
 	tokenWithPrefix := "eg1~" + tokenStr
 
-	mu.Lock()
-	newList := []ClientToken{}
-	for _, t := range tokenStore.ClientTokens {
-		if t.IP != ip {
-			newList = append(newList, t)
-		}
+	repo, err := getRepository()
+	if err != nil {
+		return "", err
 	}
-	tokenStore.ClientTokens = newList
-	tokenStore.ClientTokens = append(tokenStore.ClientTokens, ClientToken{
-		IP:        ip,
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	if err := repo.DeleteByIP(ctx, KindClient, ip); err != nil {
+		log.Error.Log("Failed to remove previous client tokens: ", err)
+		return "", err
+	}
+	err = repo.Save(ctx, StoredToken{
+		Kind:      KindClient,
 		Token:     tokenWithPrefix,
+		IP:        ip,
 		CreatedAt: now,
 		ExpiresAt: expiry,
 	})
-	mu.Unlock()
-
-	if err := saveTokens(); err != nil {
+	if err != nil {
+		log.Error.Log("Failed to save client token: ", err)
 		return "", err
 	}
 	return tokenWithPrefix, nil
 }
 
-func CreateAccess(accountId, username, clientId, grantType, deviceId string, expiresIn int) (string, error) {
+func CreateAccess(accountId, username, clientId, grantType, deviceId string, scopes []string, expiresIn int) (string, error) {
 	now := time.Now()
 	expiry := now.Add(time.Duration(expiresIn) * time.Hour)
 	claims := jwt.MapClaims{
@@ -262,6 +183,7 @@ func CreateAccess(accountId, username, clientId, grantType, deviceId string, exp
 		"clsvc":         "fortnite",
 		"t":             "s",
 		"ic":            true,
+		"scope":         scopes,
 		"jti":           strings.ReplaceAll(MakeID(), "-", ""),
 		"creation_date": now.Unix(),
 		"hours_expire":  expiresIn,
@@ -274,23 +196,7 @@ func CreateAccess(accountId, username, clientId, grantType, deviceId string, exp
 	}
 	tokenWithPrefix := "eg1~" + tokenStr
 
-	mu.Lock()
-	newList := []AccessToken{}
-	for _, t := range tokenStore.AccessTokens {
-		if t.AccountID != accountId {
-			newList = append(newList, t)
-		}
-	}
-	tokenStore.AccessTokens = newList
-	tokenStore.AccessTokens = append(tokenStore.AccessTokens, AccessToken{
-		AccountID: accountId,
-		Token:     tokenWithPrefix,
-		CreatedAt: now,
-		ExpiresAt: expiry,
-	})
-	mu.Unlock()
-
-	if err := saveTokens(); err != nil {
+	if err := replaceSessionToken(KindAccess, accountId, clientId, deviceId, tokenWithPrefix, now, expiry); err != nil {
 		return "", err
 	}
 	return tokenWithPrefix, nil
@@ -307,7 +213,6 @@ func CreateRefresh(accountId, username, clientId, grantType, deviceId string, ex
 		"clid":          clientId,
 		"iai":           accountId,
 		"am":            grantType,
-		"aud":           accountId,
 		"jti":           strings.ReplaceAll(MakeID(), "-", ""),
 		"creation_date": now.Unix(),
 		"hours_expire":  expiresIn,
@@ -320,62 +225,67 @@ func CreateRefresh(accountId, username, clientId, grantType, deviceId string, ex
 	}
 	tokenWithPrefix := "eg1~" + tokenStr
 
-	mu.Lock()
-	newList := []RefreshToken{}
-	for _, t := range tokenStore.RefreshTokens {
-		if t.AccountID != accountId {
-			newList = append(newList, t)
-		}
-	}
-	tokenStore.RefreshTokens = newList
-	tokenStore.RefreshTokens = append(tokenStore.RefreshTokens, RefreshToken{
-		AccountID: accountId,
-		Token:     tokenWithPrefix,
-		CreatedAt: now,
-		ExpiresAt: expiry,
-	})
-	mu.Unlock()
-
-	if err := saveTokens(); err != nil {
+	if err := replaceSessionToken(KindRefresh, accountId, clientId, deviceId, tokenWithPrefix, now, expiry); err != nil {
 		return "", err
 	}
 	return tokenWithPrefix, nil
 }
 
-func tokenExistsInAccessTokens(token string) bool {
-	mu.Lock()
-	defer mu.Unlock()
-	now := time.Now()
-	for _, t := range tokenStore.AccessTokens {
-		if t.Token == token && now.Before(t.ExpiresAt) {
-			return true
-		}
+// replaceAccountToken keeps a single token of the given kind per account.
+func replaceSessionToken(kind TokenKind, accountId, clientId, deviceId, token string, createdAt, expiresAt time.Time) error {
+	repo, err := getRepository()
+	if err != nil {
+		return err
 	}
-	return false
-}
+	ctx, cancel := storeContext()
+	defer cancel()
 
-func FindAccessToken(token string) *AccessToken {
-	mu.Lock()
-	defer mu.Unlock()
-	now := time.Now()
-	for i, t := range tokenStore.AccessTokens {
-		if t.Token == token && now.Before(t.ExpiresAt) {
-			return &tokenStore.AccessTokens[i]
-		}
+	if err := repo.DeleteSession(ctx, kind, accountId, clientId, deviceId); err != nil {
+		log.Error.Logf("Failed to remove previous %s tokens: %v", kind, err)
+		return err
+	}
+	err = repo.Save(ctx, StoredToken{
+		Kind:      kind,
+		Token:     token,
+		AccountID: accountId,
+		ClientID:  clientId,
+		DeviceID:  deviceId,
+		CreatedAt: createdAt,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Error.Logf("Failed to save %s token: %v", kind, err)
+		return err
 	}
 	return nil
 }
 
-func findClientToken(token string) *ClientToken {
-	mu.Lock()
-	defer mu.Unlock()
-	now := time.Now()
-	for i, t := range tokenStore.ClientTokens {
-		if t.Token == token && now.Before(t.ExpiresAt) {
-			return &tokenStore.ClientTokens[i]
-		}
+func findToken(kind TokenKind, token string) *StoredToken {
+	repo, err := getRepository()
+	if err != nil {
+		return nil
 	}
-	return nil
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	stored, err := repo.Find(ctx, kind, token)
+	if err != nil {
+		log.Error.Logf("Failed to look up %s token: %v", kind, err)
+		return nil
+	}
+	return stored
+}
+
+func FindAccessToken(token string) *AccessToken {
+	return findToken(KindAccess, token)
+}
+
+func FindRefreshToken(token string) *RefreshToken {
+	return findToken(KindRefresh, token)
+}
+
+func FindClientToken(token string) *ClientToken {
+	return findToken(KindClient, token)
 }
 
 func VerifyToken() gin.HandlerFunc {
@@ -401,158 +311,116 @@ func VerifyToken() gin.HandlerFunc {
 			authErr()
 			return
 		}
-		tokenStr := strings.TrimPrefix(tokenPart, "eg1~")
-		token, _, err := new(jwt.Parser).ParseUnverified(tokenStr, jwt.MapClaims{})
-		if err != nil {
-			authErr()
-			return
-		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			authErr()
-			return
-		}
-		tokenWithPrefix := "eg1~" + tokenStr
-		if !tokenExistsInAccessTokens(tokenWithPrefix) {
-			authErr()
-			return
-		}
-		creationDateUnix, ok := claims["creation_date"].(float64)
-		if !ok {
-			authErr()
-			return
-		}
-		hoursExpire, ok := claims["hours_expire"].(float64)
-		if !ok {
+		decoded, err := DecodeJWT(tokenPart)
+		if err != nil || decoded.TokenType != "s" || decoded.Subject == "" {
 			authErr()
 			return
 		}
-		expiry := time.Unix(int64(creationDateUnix), 0).Add(time.Duration(hoursExpire) * time.Hour)
-		if time.Now().After(expiry) {
+		if FindAccessToken(tokenPart) == nil {
 			authErr()
 			return
 		}
+		c.Set("decodedToken", decoded)
 		c.Next()
 	}
 }
 
-func FindAccessTokenIndex(token string) int {
-	for i, t := range tokenStore.AccessTokens {
-		if t.Token == token {
-			return i
+// RequireScope rejects requests whose access token was not granted every
+// listed scope. It must run after VerifyToken.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		decoded := FromContext(c)
+		for _, scope := range scopes {
+			if decoded == nil || !hasScope(decoded.Scopes, scope) {
+				c.Header("X-Epic-Error-Name", "errors.com.epicgames.common.missing_permission")
+				c.Header("X-Epic-Error-Code", "1023")
+				c.JSON(http.StatusForbidden, gin.H{
+					"errorCode":        "errors.com.epicgames.common.missing_permission",
+					"errorMessage":     fmt.Sprintf("Sorry your login does not posses the permissions '%s' needed to perform the requested operation", scope),
+					"messageVars":      []string{scope},
+					"numericErrorCode": 1023,
+				})
+				c.Abort()
+				return
+			}
 		}
+		c.Next()
 	}
-	return -1
 }
 
-func RemoveAccessToken(token string) {
-	mu.Lock()
-	defer mu.Unlock()
-	index := FindAccessTokenIndex(token)
-	if index != -1 {
-		tokenStore.AccessTokens = append(tokenStore.AccessTokens[:index], tokenStore.AccessTokens[index+1:]...)
-		if err := saveTokens(); err != nil {
-			log.Backend.Log("Failed to save tokens after removing access token: ", err)
+func hasScope(granted []string, scope string) bool {
+	for _, s := range granted {
+		if s == scope {
+			return true
 		}
 	}
+	return false
 }
 
-func FindClientTokenIndex(token string) int {
-	for i, t := range tokenStore.ClientTokens {
-		if t.Token == token {
-			return i
+// FromContext returns the access token VerifyToken validated for this request.
+func FromContext(c *gin.Context) *DecodedToken {
+	if v, ok := c.Get("decodedToken"); ok {
+		if decoded, ok := v.(*DecodedToken); ok {
+			return decoded
 		}
 	}
-	return -1
+	return nil
 }
 
-func RemoveClientToken(token string) {
-	mu.Lock()
-	defer mu.Unlock()
-	index := FindClientTokenIndex(token)
-	if index != -1 {
-		tokenStore.ClientTokens = append(tokenStore.ClientTokens[:index], tokenStore.ClientTokens[index+1:]...)
-		if err := saveTokens(); err != nil {
-			log.Backend.Log("Failed to save tokens after removing client token: ", err)
-		}
-	}
+func RemoveAccessToken(token string) {
+	RemoveTokens([]string{token})
 }
 
-func FindRefreshTokenIndex(token string) int {
-	for i, t := range tokenStore.RefreshTokens {
-		if t.Token == token {
-			return i
-		}
-	}
-	return -1
+func RemoveClientToken(token string) {
+	RemoveTokens([]string{token})
 }
 
 func RemoveRefreshToken(token string) {
-	mu.Lock()
-	defer mu.Unlock()
-	index := FindRefreshTokenIndex(token)
-	if index != -1 {
-		tokenStore.RefreshTokens = append(tokenStore.RefreshTokens[:index], tokenStore.RefreshTokens[index+1:]...)
-		if err := saveTokens(); err != nil {
-			log.Backend.Log("Failed to save tokens after removing refresh token: ", err)
-		}
-	}
-}
-
-func GetRefreshToken(index int) (RefreshToken, bool) {
-	mu.Lock()
-	defer mu.Unlock()
-	if index >= 0 && index < len(tokenStore.RefreshTokens) {
-		return tokenStore.RefreshTokens[index], true
-	}
-	return RefreshToken{}, false
+	RemoveTokens([]string{token})
 }
 
 func RemoveTokens(tokensToRemove []string) {
-	mu.Lock()
-	now := time.Now()
-
-	removeToken := func(token string) {
-		if idx := FindAccessTokenIndex(token); idx != -1 {
-			tokenStore.AccessTokens[idx].ExpiresAt = now
-			tokenStore.AccessTokens = append(tokenStore.AccessTokens[:idx], tokenStore.AccessTokens[idx+1:]...)
-		}
-		if idx := FindClientTokenIndex(token); idx != -1 {
-			tokenStore.ClientTokens[idx].ExpiresAt = now
-			tokenStore.ClientTokens = append(tokenStore.ClientTokens[:idx], tokenStore.ClientTokens[idx+1:]...)
-		}
-		if idx := FindRefreshTokenIndex(token); idx != -1 {
-			tokenStore.RefreshTokens[idx].ExpiresAt = now
-			tokenStore.RefreshTokens = append(tokenStore.RefreshTokens[:idx], tokenStore.RefreshTokens[idx+1:]...)
-		}
-	}
-
-	for _, token := range tokensToRemove {
-		removeToken(token)
+	repo, err := getRepository()
+	if err != nil {
+		log.Error.Log("Failed to remove tokens: ", err)
+		return
 	}
-	mu.Unlock()
+	ctx, cancel := storeContext()
+	defer cancel()
 
-	if err := saveTokens(); err != nil {
-		log.Error.Log("Failed to save tokens after removing tokens: ", err)
+	if err := repo.Delete(ctx, tokensToRemove); err != nil {
+		log.Error.Log("Failed to remove tokens: ", err)
 	}
 }
 
 func GetTokensByAccountID(accountID string) []string {
-	mu.Lock()
-	defer mu.Unlock()
-
 	var tokens []string
+	for _, t := range FindSessionTokens(accountID) {
+		tokens = append(tokens, t.Token)
+	}
+	return tokens
+}
 
-	for _, t := range tokenStore.AccessTokens {
-		if t.AccountID == accountID {
-			tokens = append(tokens, t.Token)
-		}
+// FindSessionTokens returns the live access and refresh tokens of an account.
+func FindSessionTokens(accountID string) []StoredToken {
+	repo, err := getRepository()
+	if err != nil {
+		return nil
 	}
-	for _, t := range tokenStore.RefreshTokens {
-		if t.AccountID == accountID {
-			tokens = append(tokens, t.Token)
-		}
+	ctx, cancel := storeContext()
+	defer cancel()
+
+	stored, err := repo.FindByAccountID(ctx, accountID)
+	if err != nil {
+		log.Error.Log("Failed to list tokens for account: ", err)
+		return nil
 	}
 
+	var tokens []StoredToken
+	for _, t := range stored {
+		if t.Kind == KindAccess || t.Kind == KindRefresh {
+			tokens = append(tokens, t)
+		}
+	}
 	return tokens
 }
@@ -0,0 +1,199 @@
+package tokens
+
+import (
+	"VentureBackend/utils"
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type TokenKind string
+
+const (
+	KindAccess  TokenKind = "access"
+	KindRefresh TokenKind = "refresh"
+	KindClient  TokenKind = "client"
+
+	KindExchangeCode       TokenKind = "exchange_code"
+	KindAuthorizationCode  TokenKind = "authorization_code"
+	KindTwoFactorChallenge TokenKind = "two_factor_challenge"
+)
+
+// StoredToken is a single issued token as persisted by a Repository.
+type StoredToken struct {
+	Kind      TokenKind `bson:"kind" json:"kind"`
+	Token     string    `bson:"token" json:"token"`
+	AccountID string    `bson:"accountId,omitempty" json:"accountId,omitempty"`
+	IP        string    `bson:"ip,omitempty" json:"ip,omitempty"`
+	ClientID  string    `bson:"clientId,omitempty" json:"clientId,omitempty"`
+	DeviceID  string    `bson:"deviceId,omitempty" json:"deviceId,omitempty"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"expiresAt"`
+}
+
+type AccessToken = StoredToken
+type RefreshToken = StoredToken
+type ClientToken = StoredToken
+
+// Repository is the storage backend for issued tokens. Lookups must never
+// return a token whose ExpiresAt is in the past.
+type Repository interface {
+	Save(ctx context.Context, token StoredToken) error
+	Find(ctx context.Context, kind TokenKind, token string) (*StoredToken, error)
+	// Consume atomically looks up and deletes a token, so one-time codes
+	// can only ever be redeemed once.
+	Consume(ctx context.Context, kind TokenKind, token string) (*StoredToken, error)
+	FindByAccountID(ctx context.Context, accountID string) ([]StoredToken, error)
+	Delete(ctx context.Context, tokens []string) error
+	// DeleteSession removes the tokens of one kind issued to a single
+	// session, identified by its client and device.
+	DeleteSession(ctx context.Context, kind TokenKind, accountID, clientID, deviceID string) error
+	DeleteByIP(ctx context.Context, kind TokenKind, ip string) error
+}
+
+var (
+	ErrStoreNotInitialized = errors.New("token store not initialized")
+	repository             Repository
+)
+
+// SetRepository swaps the backend used by every token function.
+func SetRepository(repo Repository) {
+	mu.Lock()
+	defer mu.Unlock()
+	repository = repo
+}
+
+func getRepository() (Repository, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if repository == nil {
+		return nil, ErrStoreNotInitialized
+	}
+	return repository, nil
+}
+
+// InitTokenStore binds the token functions to the "tokens" collection. It has
+// to run after utils.InitMongoDB.
+func InitTokenStore() error {
+	if utils.MongoClient == nil {
+		return ErrStoreNotInitialized
+	}
+	collection := utils.MongoClient.Database(os.Getenv("DB_NAME")).Collection("tokens")
+	repo, err := NewMongoRepository(collection)
+	if err != nil {
+		utils.MongoDB.Log("Failed to create token indexes:", err)
+		return err
+	}
+	SetRepository(repo)
+	return nil
+}
+
+type MongoRepository struct {
+	collection *mongo.Collection
+}
+
+func NewMongoRepository(collection *mongo.Collection) (*MongoRepository, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+		{
+			Keys:    bson.D{{Key: "token", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "accountId", Value: 1}, {Key: "kind", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "ip", Value: 1}, {Key: "kind", Value: 1}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &MongoRepository{collection: collection}, nil
+}
+
+func (r *MongoRepository) Save(ctx context.Context, token StoredToken) error {
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+func (r *MongoRepository) Find(ctx context.Context, kind TokenKind, token string) (*StoredToken, error) {
+	var stored StoredToken
+	err := r.collection.FindOne(ctx, bson.M{
+		"kind":      kind,
+		"token":     token,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *MongoRepository) Consume(ctx context.Context, kind TokenKind, token string) (*StoredToken, error) {
+	var stored StoredToken
+	err := r.collection.FindOneAndDelete(ctx, bson.M{
+		"kind":      kind,
+		"token":     token,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}).Decode(&stored)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &stored, nil
+}
+
+func (r *MongoRepository) FindByAccountID(ctx context.Context, accountID string) ([]StoredToken, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"accountId": accountID,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var stored []StoredToken
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+func (r *MongoRepository) Delete(ctx context.Context, tokens []string) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"token": bson.M{"$in": tokens}})
+	return err
+}
+
+func (r *MongoRepository) DeleteSession(ctx context.Context, kind TokenKind, accountID, clientID, deviceID string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{
+		"kind":      kind,
+		"accountId": accountID,
+		"clientId":  clientID,
+		"deviceId":  deviceID,
+	})
+	return err
+}
+
+func (r *MongoRepository) DeleteByIP(ctx context.Context, kind TokenKind, ip string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"kind": kind, "ip": ip})
+	return err
+}
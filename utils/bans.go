@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// HWIDHeader is the request header the client sends its hardware id in.
+const HWIDHeader = "hwid"
+
+var BanCollection *mongo.Collection
+
+// InitBans binds the bans collection. It has to run after InitMongoDB.
+func InitBans() error {
+	BanCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("bans")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := BanCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "type", Value: 1}, {Key: "value", Value: 1}}},
+		{Keys: bson.D{{Key: "accountId", Value: 1}}},
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create ban indexes:", err)
+	}
+	return err
+}
+
+// FindActiveBan returns the first ban matching any of the given identifiers
+// that is neither lifted nor expired. Empty identifiers are skipped.
+func FindActiveBan(accountId, ip, hwid string) (*models.Ban, error) {
+	if BanCollection == nil {
+		return nil, nil
+	}
+
+	var targets []bson.M
+	if accountId != "" {
+		targets = append(targets, bson.M{"type": models.BanAccount, "value": accountId})
+	}
+	if ip != "" {
+		targets = append(targets, bson.M{"type": models.BanIP, "value": ip})
+	}
+	if hwid != "" {
+		targets = append(targets, bson.M{"type": models.BanHWID, "value": hwid})
+	}
+	if len(targets) == 0 {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"lifted": false,
+		"$and": []bson.M{
+			{"$or": targets},
+			{"$or": []bson.M{
+				{"expiresAt": nil},
+				{"expiresAt": bson.M{"$gt": time.Now()}},
+			}},
+		},
+	}
+
+	var ban models.Ban
+	err := BanCollection.FindOne(ctx, filter).Decode(&ban)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func CreateBan(ban models.Ban) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ban.Created = time.Now().UTC()
+	_, err := BanCollection.InsertOne(ctx, ban)
+	return err
+}
+
+// LiftBans marks every active ban issued against the account as lifted,
+// including IP and HWID bans that were recorded for it.
+func LiftBans(accountId string) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := BanCollection.UpdateMany(ctx,
+		bson.M{"accountId": accountId, "lifted": false},
+		bson.M{"$set": bson.M{"lifted": true}},
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// RecordLoginOrigin stores the last IP and hwid an account logged in from so
+// bans can target them later.
+func RecordLoginOrigin(accountId, ip, hwid string) {
+	set := bson.M{}
+	if ip != "" {
+		set["lastIp"] = ip
+	}
+	if hwid != "" {
+		set["lastHwid"] = hwid
+	}
+	if len(set) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := UserCollection.UpdateOne(ctx, bson.M{"accountId": accountId}, bson.M{"$set": set}); err != nil {
+		Error.Log("Failed to record login origin:", err)
+	}
+}
+
+// trustedProxies are the networks whose X-Forwarded-For and X-Real-IP
+// headers are believed. Set them with SetTrustedProxies.
+var trustedProxies []*net.IPNet
+
+// TrustedProxies reads the comma separated IPs and CIDRs of TRUSTED_PROXIES.
+// Nothing is trusted by default.
+func TrustedProxies() []string {
+	proxies := []string{}
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			proxies = append(proxies, proxy)
+		}
+	}
+	return proxies
+}
+
+// SetTrustedProxies sets the proxies RequestIP believes, matching gin's
+// Engine.SetTrustedProxies, which should be given the same list.
+func SetTrustedProxies(proxies []string) error {
+	networks := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return &net.ParseError{Type: "IP address", Text: proxy}
+			}
+			bits := net.IPv6len * 8
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), net.IPv4len*8
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return err
+		}
+		networks = append(networks, network)
+	}
+	trustedProxies = networks
+	return nil
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestIP returns the client address of a raw request the same way gin's
+// ClientIP does: the proxy headers are only read when the request came from
+// a trusted proxy, and X-Forwarded-For is walked back past trusted hops.
+func RequestIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	remote := net.ParseIP(host)
+	if remote == nil || !isTrustedProxy(remote) {
+		return host
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		hops := strings.Split(forwarded, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			ip := net.ParseIP(hop)
+			if ip == nil {
+				break
+			}
+			if i == 0 || !isTrustedProxy(ip) {
+				return hop
+			}
+		}
+	}
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return host
+}
+
+// BanMessage describes a ban for the player, including when it ends.
+func BanMessage(ban *models.Ban) string {
+	msg := "You have been banned from Fortnite"
+	if ban.ExpiresAt == nil {
+		msg = "You have been permanently banned from Fortnite"
+	} else {
+		msg += " until " + ban.ExpiresAt.UTC().Format(time.RFC1123)
+	}
+	if ban.Reason != "" {
+		msg += ". Reason: " + ban.Reason
+	}
+	return msg + "."
+}
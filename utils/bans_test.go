@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestRequestIPTrustsOnlyConfiguredProxies(t *testing.T) {
+	if err := SetTrustedProxies([]string{"10.0.0.1", "192.168.0.0/16"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	t.Cleanup(func() { trustedProxies = nil })
+
+	for _, tt := range []struct {
+		name, remote, forwarded, realIP, want string
+	}{
+		{"direct client ignores headers", "203.0.113.5:1234", "1.2.3.4", "5.6.7.8", "203.0.113.5"},
+		{"trusted proxy", "10.0.0.1:1234", "1.2.3.4", "", "1.2.3.4"},
+		{"spoofed hop before trusted chain", "10.0.0.1:1234", "6.6.6.6, 1.2.3.4, 192.168.1.9", "", "1.2.3.4"},
+		{"all hops trusted", "10.0.0.1:1234", "192.168.1.8, 192.168.1.9", "", "192.168.1.8"},
+		{"invalid hop falls back to X-Real-IP", "10.0.0.1:1234", "nonsense", "5.6.7.8", "5.6.7.8"},
+		{"no headers", "192.168.3.3:1234", "", "", "192.168.3.3"},
+	} {
+		r, _ := http.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tt.remote
+		if tt.forwarded != "" {
+			r.Header.Set("X-Forwarded-For", tt.forwarded)
+		}
+		if tt.realIP != "" {
+			r.Header.Set("X-Real-IP", tt.realIP)
+		}
+		if got := RequestIP(r); got != tt.want {
+			t.Errorf("%s: RequestIP = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetTrustedProxiesRejectsInvalid(t *testing.T) {
+	t.Cleanup(func() { trustedProxies = nil })
+	if err := SetTrustedProxies([]string{"not-an-ip"}); err == nil {
+		t.Errorf("SetTrustedProxies accepted an invalid proxy")
+	}
+}
@@ -0,0 +1,167 @@
+package utils
+
+import "strings"
+
+// DynamicBundleItem is one item of a dynamic bundle and what owning it takes
+// off the bundle's price.
+type DynamicBundleItem struct {
+	CanOwnMultiple             bool                   `json:"bCanOwnMultiple"`
+	RegularPrice               int                    `json:"regularPrice"`
+	DiscountedPrice            int                    `json:"discountedPrice"`
+	AlreadyOwnedPriceReduction int                    `json:"alreadyOwnedPriceReduction"`
+	Item                       map[string]interface{} `json:"item"`
+}
+
+// DynamicBundleInfo is how the client explains a bundle's price. The bundle
+// costs DiscountedBasePrice minus the reduction of every item the player
+// owns, but never less than FloorPrice.
+type DynamicBundleInfo struct {
+	DiscountedBasePrice int                 `json:"discountedBasePrice"`
+	RegularBasePrice    int                 `json:"regularBasePrice"`
+	FloorPrice          int                 `json:"floorPrice"`
+	CurrencyType        string              `json:"currencyType"`
+	CurrencySubType     string              `json:"currencySubType"`
+	DisplayType         string              `json:"displayType"`
+	BundleItems         []DynamicBundleItem `json:"bundleItems"`
+}
+
+// BundleFloorPrice is the least a V-Bucks bundle costs however much of it the
+// player owns, unless its catalog_config entry sets floorPrice. Read from
+// BUNDLE_FLOOR_PRICE, 100 by default.
+func BundleFloorPrice() int {
+	return envInt("BUNDLE_FLOOR_PRICE", 100)
+}
+
+// newDynamicBundleInfo prices a bundle of templateIDs sold for price. Items
+// are worth their itemPrices entry, or what the shop rotation sells them for,
+// and the bundle's discount is shared between them by worth. The default floor
+// is a V-Bucks amount, so bundles sold for anything else only have a floor
+// when floorPrice sets one in their own currency.
+func newDynamicBundleInfo(templateIDs []string, price int, currencyType, currencySubType string, itemPrices map[string]interface{}, floorPrice interface{}) *DynamicBundleInfo {
+	rotation, _ := LoadShopRotationConfig()
+
+	info := &DynamicBundleInfo{
+		DiscountedBasePrice: price,
+		CurrencyType:        currencyType,
+		CurrencySubType:     currencySubType,
+		DisplayType:         "AmountOff",
+		BundleItems:         []DynamicBundleItem{},
+	}
+	if strings.EqualFold(currencyType, "MtxCurrency") {
+		info.FloorPrice = min(BundleFloorPrice(), price)
+	}
+	if floorPrice != nil {
+		info.FloorPrice = min(max(toInt(floorPrice), 0), price)
+	}
+
+	for _, templateID := range templateIDs {
+		regular := 0
+		for key, value := range itemPrices {
+			if strings.EqualFold(key, templateID) {
+				regular = toInt(value)
+				break
+			}
+		}
+		if regular <= 0 && rotation != nil {
+			_, regular = rotation.ItemPrice(templateID)
+		}
+		info.RegularBasePrice += regular
+		info.BundleItems = append(info.BundleItems, DynamicBundleItem{
+			RegularPrice: regular,
+			Item:         map[string]interface{}{"templateId": templateID, "quantity": 1},
+		})
+	}
+
+	// Without a worth for any item they share the price evenly. The most
+	// valuable item takes the rounding so the shares add up to the price.
+	left, top := price, 0
+	for i := range info.BundleItems {
+		item := &info.BundleItems[i]
+		if info.RegularBasePrice > 0 {
+			item.DiscountedPrice = price * item.RegularPrice / info.RegularBasePrice
+		} else {
+			item.DiscountedPrice = price / len(info.BundleItems)
+		}
+		left -= item.DiscountedPrice
+		if item.RegularPrice > info.BundleItems[top].RegularPrice {
+			top = i
+		}
+	}
+	if len(info.BundleItems) > 0 {
+		info.BundleItems[top].DiscountedPrice += left
+	}
+	for i := range info.BundleItems {
+		info.BundleItems[i].AlreadyOwnedPriceReduction = info.BundleItems[i].DiscountedPrice
+	}
+	if info.RegularBasePrice == 0 {
+		info.RegularBasePrice = price
+	}
+	return info
+}
+
+// Price is what the bundle costs a player owning the templates in owned,
+// keyed lower case.
+func (info *DynamicBundleInfo) Price(owned map[string]bool) int {
+	price := info.DiscountedBasePrice
+	for _, item := range info.BundleItems {
+		templateID, _ := item.Item["templateId"].(string)
+		if owned[strings.ToLower(templateID)] {
+			price -= item.AlreadyOwnedPriceReduction
+		}
+	}
+	return max(price, info.FloorPrice)
+}
+
+// OwnedTemplates lists the templates of a profile's items, lower case.
+func OwnedTemplates(profile map[string]interface{}) map[string]bool {
+	owned := map[string]bool{}
+	items, _ := profile["items"].(map[string]interface{})
+	for _, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if templateID, ok := item["templateId"].(string); ok {
+			owned[strings.ToLower(templateID)] = true
+		}
+	}
+	return owned
+}
+
+// PriceBundle sets entry's final price to what it costs a player owning
+// owned. Entries that aren't dynamic bundles are left alone.
+func PriceBundle(entry *CatalogEntry, owned map[string]bool) {
+	if entry.DynamicBundleInfo == nil || len(entry.Prices) == 0 {
+		return
+	}
+	entry.Prices[0]["finalPrice"] = entry.DynamicBundleInfo.Price(owned)
+}
+
+// ApplyBundlePricing prices the dynamic bundles in catalog for accountId
+// from what its athena profile owns.
+func ApplyBundlePricing(catalog map[string]interface{}, accountId string) error {
+	profiles, err := FindProfileByAccountID(accountId)
+	if err != nil {
+		return err
+	}
+	athena, _ := profiles.Profiles["athena"].(map[string]interface{})
+	owned := OwnedTemplates(athena)
+
+	storefronts, _ := catalog["storefronts"].([]interface{})
+	for _, sf := range storefronts {
+		store, ok := sf.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entries, _ := store["catalogEntries"].([]interface{})
+		for i, e := range entries {
+			entry, ok := e.(CatalogEntry)
+			if !ok || entry.DynamicBundleInfo == nil {
+				continue
+			}
+			PriceBundle(&entry, owned)
+			entries[i] = entry
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package utils
+
+import "testing"
+
+func testBundle(currencyType string, floorPrice interface{}) *DynamicBundleInfo {
+	return newDynamicBundleInfo(
+		[]string{"AthenaCharacter:cid_a", "AthenaBackpack:bid_a", "AthenaPickaxe:pickaxe_a"},
+		1800, currencyType, "",
+		map[string]interface{}{
+			"athenacharacter:cid_a":   1500,
+			"AthenaBackpack:bid_a":    500,
+			"AthenaPickaxe:pickaxe_a": 500,
+		},
+		floorPrice)
+}
+
+func TestDynamicBundleSharesPriceByWorth(t *testing.T) {
+	info := testBundle("MtxCurrency", nil)
+
+	if info.RegularBasePrice != 2500 {
+		t.Errorf("RegularBasePrice = %d, want 2500", info.RegularBasePrice)
+	}
+	total := 0
+	for _, item := range info.BundleItems {
+		total += item.DiscountedPrice
+		if item.AlreadyOwnedPriceReduction != item.DiscountedPrice {
+			t.Errorf("%v reduction %d differs from its share %d", item.Item["templateId"], item.AlreadyOwnedPriceReduction, item.DiscountedPrice)
+		}
+	}
+	if total != 1800 {
+		t.Errorf("shares add up to %d, want 1800", total)
+	}
+	if info.BundleItems[0].DiscountedPrice != 1080 {
+		t.Errorf("character share = %d, want 1080", info.BundleItems[0].DiscountedPrice)
+	}
+}
+
+func TestDynamicBundlePrice(t *testing.T) {
+	info := testBundle("MtxCurrency", nil)
+
+	if got := info.Price(map[string]bool{}); got != 1800 {
+		t.Errorf("nothing owned: %d, want 1800", got)
+	}
+	if got := info.Price(map[string]bool{"athenabackpack:bid_a": true}); got != 1440 {
+		t.Errorf("backpack owned: %d, want 1440", got)
+	}
+	owned := map[string]bool{"athenacharacter:cid_a": true, "athenabackpack:bid_a": true, "athenapickaxe:pickaxe_a": true}
+	if got := info.Price(owned); got != BundleFloorPrice() {
+		t.Errorf("everything owned: %d, want the floor %d", got, BundleFloorPrice())
+	}
+}
+
+func TestDynamicBundleFloorPrice(t *testing.T) {
+	owned := map[string]bool{"athenacharacter:cid_a": true, "athenabackpack:bid_a": true, "athenapickaxe:pickaxe_a": true}
+
+	if got := testBundle("MtxCurrency", 300).Price(owned); got != 300 {
+		t.Errorf("configured floor: %d, want 300", got)
+	}
+	if got := testBundle("GameItem", nil).Price(owned); got != 0 {
+		t.Errorf("GameItem bundle took the V-Bucks floor: %d", got)
+	}
+	if got := testBundle("GameItem", 5).Price(owned); got != 5 {
+		t.Errorf("GameItem bundle with its own floor: %d, want 5", got)
+	}
+}
+
+func TestPriceBundle(t *testing.T) {
+	entry := &CatalogEntry{
+		DynamicBundleInfo: testBundle("MtxCurrency", nil),
+		Prices:            []map[string]interface{}{{"currencyType": "MtxCurrency", "finalPrice": 1800}},
+	}
+	PriceBundle(entry, map[string]bool{"athenapickaxe:pickaxe_a": true})
+	if got := entry.Prices[0]["finalPrice"]; got != 1440 {
+		t.Errorf("finalPrice = %v, want 1440", got)
+	}
+
+	plain := &CatalogEntry{Prices: []map[string]interface{}{{"finalPrice": 800}}}
+	PriceBundle(plain, map[string]bool{"athenapickaxe:pickaxe_a": true})
+	if got := plain.Prices[0]["finalPrice"]; got != 800 {
+		t.Errorf("plain offer repriced to %v", got)
+	}
+}
+
+func TestOwnedTemplates(t *testing.T) {
+	profile := map[string]interface{}{
+		"items": map[string]interface{}{
+			"a": map[string]interface{}{"templateId": "AthenaCharacter:CID_A"},
+			"b": "not an item",
+		},
+	}
+	owned := OwnedTemplates(profile)
+	if !owned["athenacharacter:cid_a"] || len(owned) != 1 {
+		t.Errorf("OwnedTemplates = %v", owned)
+	}
+}
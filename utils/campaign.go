@@ -0,0 +1,202 @@
+package utils
+
+import (
+	"encoding/json"
+	"math/rand"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type CampaignReward struct {
+	TemplateID string `json:"templateId"`
+	Quantity   int    `json:"quantity"`
+	Weight     int    `json:"weight,omitempty"`
+	Level      int    `json:"level,omitempty"`
+}
+
+// CampaignItemType describes how items of one template type, e.g.
+// "Schematic", are levelled, recycled and collected.
+type CampaignItemType struct {
+	UpgradeResource string `json:"upgradeResource"`
+	UpgradeCost     int    `json:"upgradeCost"`
+	RecycleResource string `json:"recycleResource"`
+	RecycleValue    int    `json:"recycleValue"`
+	CollectionBook  string `json:"collectionBook"`
+	BookXP          int    `json:"bookXp"`
+}
+
+type CampaignSquad struct {
+	Slots     int      `json:"slots"`
+	ItemTypes []string `json:"itemTypes"`
+}
+
+type CampaignCardPack struct {
+	Drops int              `json:"drops"`
+	Loot  []CampaignReward `json:"loot"`
+}
+
+type CampaignMissionAlert struct {
+	Rewards []CampaignReward `json:"rewards"`
+}
+
+type CampaignCollectionBook struct {
+	XPPerLevel int              `json:"xpPerLevel"`
+	Rewards    []CampaignReward `json:"rewards"`
+}
+
+// CampaignData is the Save the World template the campaign operations
+// validate against.
+type CampaignData struct {
+	HomebaseName struct {
+		MinLength int `json:"minLength"`
+		MaxLength int `json:"maxLength"`
+	} `json:"homebaseName"`
+	StackableTypes []string                        `json:"stackableTypes"`
+	Rarities       map[string]int                  `json:"rarities"`
+	TierMaxLevel   map[string]int                  `json:"tierMaxLevel"`
+	ItemTypes      map[string]CampaignItemType     `json:"itemTypes"`
+	Squads         map[string]CampaignSquad        `json:"squads"`
+	CardPacks      map[string]CampaignCardPack     `json:"cardPacks"`
+	MissionAlerts  map[string]CampaignMissionAlert `json:"missionAlerts"`
+	CollectionBook CampaignCollectionBook          `json:"collectionBook"`
+}
+
+var (
+	campaignOnce sync.Once
+	campaignData *CampaignData
+	campaignErr  error
+
+	campaignRarity = regexp.MustCompile(`_(c|uc|r|vr|sr)_`)
+	campaignTier   = regexp.MustCompile(`_t0?(\d+)$`)
+)
+
+// LoadCampaign reads static/responses/Campaign.json, caching it after the
+// first read.
+func LoadCampaign() (*CampaignData, error) {
+	campaignOnce.Do(func() {
+		raw, err := os.ReadFile("./static/responses/Campaign.json")
+		if err != nil {
+			Error.Logf("Failed to read campaign file: %v", err)
+			campaignErr = err
+			return
+		}
+		var data CampaignData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			Error.Logf("Failed to parse campaign: %v", err)
+			campaignErr = err
+			return
+		}
+		campaignData = &data
+	})
+	return campaignData, campaignErr
+}
+
+// TemplateType is the part of a templateId before the colon.
+func TemplateType(templateID string) string {
+	itemType, _, _ := strings.Cut(templateID, ":")
+	return itemType
+}
+
+// ItemType returns the definition for templateID's type.
+func (d *CampaignData) ItemType(templateID string) (*CampaignItemType, bool) {
+	for name, itemType := range d.ItemTypes {
+		if strings.EqualFold(name, TemplateType(templateID)) {
+			return &itemType, true
+		}
+	}
+	return nil, false
+}
+
+// Stackable reports whether copies of templateID share one item.
+func (d *CampaignData) Stackable(templateID string) bool {
+	for _, itemType := range d.StackableTypes {
+		if strings.EqualFold(itemType, TemplateType(templateID)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Rarity is the multiplier for the rarity in templateID, 1 when it has none.
+func (d *CampaignData) Rarity(templateID string) int {
+	if match := campaignRarity.FindStringSubmatch(strings.ToLower(templateID)); match != nil {
+		if multiplier, ok := d.Rarities[match[1]]; ok {
+			return multiplier
+		}
+	}
+	return 1
+}
+
+// Tier is the tier in templateID, 1 when it has none.
+func (d *CampaignData) Tier(templateID string) int {
+	if match := campaignTier.FindStringSubmatch(strings.ToLower(templateID)); match != nil {
+		if tier, err := strconv.Atoi(match[1]); err == nil && tier > 0 {
+			return tier
+		}
+	}
+	return 1
+}
+
+// MaxLevel is the highest level an item of templateID's tier can reach.
+func (d *CampaignData) MaxLevel(templateID string) int {
+	return d.TierMaxLevel[strconv.Itoa(d.Tier(templateID))]
+}
+
+// UpgradeCost is what raising an item from level to level+1 costs.
+func (d *CampaignData) UpgradeCost(itemType *CampaignItemType, templateID string, level int) int {
+	return itemType.UpgradeCost * max(level, 1) * d.Rarity(templateID)
+}
+
+// RecycleValue is what recycling an item returns: its base value plus half
+// of what was spent levelling it.
+func (d *CampaignData) RecycleValue(itemType *CampaignItemType, templateID string, level int) int {
+	spent := 0
+	for l := 1; l < level; l++ {
+		spent += d.UpgradeCost(itemType, templateID, l)
+	}
+	return itemType.RecycleValue*d.Rarity(templateID)*d.Tier(templateID) + spent/2
+}
+
+// FindCardPack returns the card pack with templateID.
+func (d *CampaignData) FindCardPack(templateID string) (*CampaignCardPack, bool) {
+	for name, pack := range d.CardPacks {
+		if strings.EqualFold(name, templateID) {
+			return &pack, true
+		}
+	}
+	return nil, false
+}
+
+// Roll draws the pack's drops by weight.
+func (p *CampaignCardPack) Roll() []CampaignReward {
+	total := 0
+	for _, loot := range p.Loot {
+		total += loot.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	drops := make([]CampaignReward, 0, p.Drops)
+	for i := 0; i < p.Drops; i++ {
+		pick := rand.Intn(total)
+		for _, loot := range p.Loot {
+			if pick < loot.Weight {
+				drops = append(drops, loot)
+				break
+			}
+			pick -= loot.Weight
+		}
+	}
+	return drops
+}
+
+// BookLevel is the collection book level book XP reaches.
+func (b *CampaignCollectionBook) BookLevel(xp int) int {
+	if b.XPPerLevel <= 0 {
+		return 0
+	}
+	return xp / b.XPPerLevel
+}
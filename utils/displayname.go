@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrDisplayNameInvalid   = errors.New("display name is invalid")
+	ErrDisplayNameTaken     = errors.New("display name is already taken")
+	ErrDisplayNameUnchanged = errors.New("display name is unchanged")
+	ErrDisplayNameChanged   = errors.New("display name was changed by another request")
+)
+
+// DisplayNameCooldownError is returned while an account still has to wait
+// before changing its display name again.
+type DisplayNameCooldownError struct {
+	NextChange time.Time
+}
+
+func (e DisplayNameCooldownError) Error() string {
+	return "display name can be changed again at " + e.NextChange.Format(time.RFC3339)
+}
+
+// InitDisplayNames backfills username_lower on older accounts and puts a
+// unique index on it, so two renames can't race to the same name. It has to
+// run after InitMongoDB.
+func InitDisplayNames() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := UserCollection.UpdateMany(ctx,
+		bson.M{"$expr": bson.M{"$ne": bson.A{"$username_lower", bson.M{"$toLower": "$username"}}}},
+		mongo.Pipeline{{{Key: "$set", Value: bson.M{"username_lower": bson.M{"$toLower": "$username"}}}}})
+	if err != nil {
+		MongoDB.Log("Failed to backfill username_lower:", err)
+		return err
+	}
+	if res.ModifiedCount > 0 {
+		MongoDB.Logf("Backfilled username_lower on %d accounts", res.ModifiedCount)
+	}
+
+	_, err = UserCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username_lower", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create unique username index, check for accounts sharing a name:", err)
+	}
+	return err
+}
+
+const displayNameChars = " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
+
+// DisplayNameCooldown is read from DISPLAY_NAME_COOLDOWN_DAYS, 14 days by default.
+func DisplayNameCooldown() time.Duration {
+	days := 14
+	if raw := os.Getenv("DISPLAY_NAME_COOLDOWN_DAYS"); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			days = v
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// NextDisplayNameChange returns when the user may change their name again.
+func NextDisplayNameChange(user *models.User) time.Time {
+	if user.LastNameChange.IsZero() {
+		return time.Time{}
+	}
+	return user.LastNameChange.Add(DisplayNameCooldown())
+}
+
+func ValidateDisplayName(name string) error {
+	if len(name) < 3 || len(name) >= 25 || strings.TrimSpace(name) != name {
+		return ErrDisplayNameInvalid
+	}
+	for _, char := range name {
+		if !strings.ContainsRune(displayNameChars, char) {
+			return ErrDisplayNameInvalid
+		}
+	}
+	return nil
+}
+
+// ChangeDisplayName renames the account after checking the name, its
+// case-insensitive uniqueness and the cooldown. The unique index on
+// username_lower settles races the lookup can't see, and the update only
+// matches while the account still has the name and is out of its cooldown, so
+// parallel renames can't both go through. The old name is kept in the user's
+// nameHistory along with who made the change.
+func ChangeDisplayName(user *models.User, newName, changedBy string) error {
+	if err := ValidateDisplayName(newName); err != nil {
+		return err
+	}
+	if newName == user.Username {
+		return ErrDisplayNameUnchanged
+	}
+	if next := NextDisplayNameChange(user); time.Now().Before(next) {
+		return DisplayNameCooldownError{NextChange: next}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := UserCollection.CountDocuments(ctx, bson.M{
+		"username_lower": strings.ToLower(newName),
+		"accountId":      bson.M{"$ne": user.AccountID},
+	})
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDisplayNameTaken
+	}
+
+	now := time.Now().UTC()
+	res, err := UserCollection.UpdateOne(ctx,
+		bson.M{
+			"accountId": user.AccountID,
+			"username":  user.Username,
+			"$or": bson.A{
+				bson.M{"lastNameChange": nil},
+				bson.M{"lastNameChange": bson.M{"$lte": now.Add(-DisplayNameCooldown())}},
+			},
+		},
+		bson.M{
+			"$set": bson.M{
+				"username":       newName,
+				"username_lower": strings.ToLower(newName),
+				"lastNameChange": now,
+			},
+			"$push": bson.M{"nameHistory": models.NameChange{
+				OldName:   user.Username,
+				NewName:   newName,
+				ChangedBy: changedBy,
+				ChangedAt: now,
+			}},
+		})
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrDisplayNameTaken
+	}
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// Another rename got there first; report its cooldown if it started one.
+		if current, err := FindUserByAccountID(user.AccountID); err == nil && current != nil {
+			if next := NextDisplayNameChange(current); now.Before(next) {
+				return DisplayNameCooldownError{NextChange: next}
+			}
+		}
+		return ErrDisplayNameChanged
+	}
+
+	Backend.Logf("%s changed display name of %s to %s", changedBy, user.Username, newName)
+	user.Username = newName
+	user.LastNameChange = now
+	return nil
+}
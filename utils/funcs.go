@@ -106,6 +106,14 @@ func VerifyPassword(input, stored string) bool {
 	return err == nil
 }
 
+func HashSecret(secret string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
 func GenerateClientToken(clientID string, duration time.Duration) (string, time.Duration) {
 	claims := jwt.RegisteredClaims{
 		Subject:   clientID,
@@ -280,9 +288,8 @@ func RegisterUser(discordId *string, username, email, plainPassword string, isSe
 		}
 	}
 
-	allowedChars := " !\"#$%&'()*+,-./0123456789:;<=>?@ABCDEFGHIJKLMNOPQRSTUVWXYZ[\\]^_`abcdefghijklmnopqrstuvwxyz{|}~"
 	for _, char := range username {
-		if !strings.ContainsRune(allowedChars, char) {
+		if !strings.ContainsRune(displayNameChars, char) {
 			Error.Log("RegisterUser: Username contains invalid characters")
 			return false
 		}
@@ -299,16 +306,17 @@ func RegisterUser(discordId *string, username, email, plainPassword string, isSe
 	createdTime := time.Now().UTC().Format(time.RFC3339)
 
 	userDoc := bson.M{
-		"created":       createdTime,
-		"banned":        false,
-		"discordId":     discordId,
-		"accountId":     accountId,
-		"username":      username,
-		"email":         email,
-		"password":      string(hashedPassword),
-		"matchmakingId": matchmakingId,
-		"isServer":      isServer,
-		"acceptedEULA":  false,
+		"created":        createdTime,
+		"banned":         false,
+		"discordId":      discordId,
+		"accountId":      accountId,
+		"username":       username,
+		"username_lower": strings.ToLower(username),
+		"email":          email,
+		"password":       string(hashedPassword),
+		"matchmakingId":  matchmakingId,
+		"isServer":       isServer,
+		"acceptedEULA":   false,
 	}
 	_, err = UserCollection.InsertOne(context.TODO(), userDoc)
 	if err != nil {
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	GiftCollection        *mongo.Collection
+	GiftCounterCollection *mongo.Collection
+)
+
+// InitGifts binds the gifts ledger and the daily gift counters. It has to run
+// after InitMongoDB.
+func InitGifts() error {
+	db := MongoClient.Database(os.Getenv("DB_NAME"))
+	GiftCollection = db.Collection("gifts")
+	GiftCounterCollection = db.Collection("gift_counters")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := GiftCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "senderId", Value: 1}, {Key: "created", Value: -1}}},
+		{Keys: bson.D{{Key: "receiverId", Value: 1}, {Key: "created", Value: -1}}},
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create gift indexes:", err)
+		return err
+	}
+
+	_, err = GiftCounterCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expireAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create gift counter indexes:", err)
+	}
+	return err
+}
+
+func envInt(name string, def int) int {
+	if raw := os.Getenv(name); raw != "" {
+		if v, err := strconv.Atoi(raw); err == nil && v >= 0 {
+			return v
+		}
+	}
+	return def
+}
+
+// GiftDailySendLimit is how many gifts an account may send per UTC day, read
+// from GIFT_DAILY_SEND_LIMIT. 0 disables the cap; 5 by default.
+func GiftDailySendLimit() int {
+	return envInt("GIFT_DAILY_SEND_LIMIT", 5)
+}
+
+// GiftDailyReceiveLimit is how many gifts an account may receive per UTC
+// day, read from GIFT_DAILY_RECEIVE_LIMIT. 0 disables the cap; 5 by default.
+func GiftDailyReceiveLimit() int {
+	return envInt("GIFT_DAILY_RECEIVE_LIMIT", 5)
+}
+
+// GiftMinFriendshipAge is how long two accounts must have been friends
+// before one can gift the other, read from GIFT_MIN_FRIENDSHIP_HOURS,
+// 48 hours by default.
+func GiftMinFriendshipAge() time.Duration {
+	return time.Duration(envInt("GIFT_MIN_FRIENDSHIP_HOURS", 48)) * time.Hour
+}
+
+func RecordGift(gift models.Gift) error {
+	if GiftCollection == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if gift.Created.IsZero() {
+		gift.Created = time.Now().UTC()
+	}
+	_, err := GiftCollection.InsertOne(ctx, gift)
+	return err
+}
+
+// GiftSlot is a reservation against one of the daily gift caps.
+type GiftSlot struct {
+	Field     string
+	AccountID string
+	Day       string
+	Count     int
+}
+
+func (slot GiftSlot) counterID() string {
+	return slot.Field + ":" + slot.AccountID + ":" + slot.Day
+}
+
+// ReserveGiftSlots takes count gifts from today's cap on field ("senderId" or
+// "receiverId") for accountId. The counter is only raised while it stays
+// within limit, so parallel gifts can't go over the cap between checking and
+// delivering. It returns false when the cap would be exceeded.
+func ReserveGiftSlots(field, accountId string, count, limit int) (*GiftSlot, bool, error) {
+	now := time.Now().UTC()
+	slot := &GiftSlot{Field: field, AccountID: accountId, Day: now.Format("2006-01-02"), Count: count}
+	if GiftCounterCollection == nil {
+		return slot, true, nil
+	}
+	if count > limit {
+		return nil, false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A full counter doesn't match the filter, so the upsert tries to insert
+	// a second document with the same _id and fails with a duplicate key.
+	_, err := GiftCounterCollection.UpdateOne(ctx,
+		bson.M{"_id": slot.counterID(), "count": bson.M{"$lte": limit - count}},
+		bson.M{
+			"$inc":         bson.M{"count": count},
+			"$setOnInsert": bson.M{"expireAt": now.Truncate(24 * time.Hour).Add(48 * time.Hour)},
+		},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return slot, true, nil
+}
+
+// ReleaseGiftSlots hands back reserved slots for gifts that were not sent.
+func ReleaseGiftSlots(slots ...*GiftSlot) {
+	if GiftCounterCollection == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, slot := range slots {
+		if slot == nil || slot.Count == 0 {
+			continue
+		}
+		_, err := GiftCounterCollection.UpdateOne(ctx,
+			bson.M{"_id": slot.counterID()},
+			bson.M{"$inc": bson.M{"count": -slot.Count}})
+		if err != nil {
+			Error.Logf("Failed to release %d gift slots of %s: %v", slot.Count, slot.counterID(), err)
+		}
+	}
+}
+
+// FindGifts lists the gifts an account sent or received, newest first.
+func FindGifts(accountId string, limit int64) ([]models.Gift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"$or": []bson.M{{"senderId": accountId}, {"receiverId": accountId}}}
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: -1}}).SetLimit(limit)
+	cursor, err := GiftCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	gifts := []models.Gift{}
+	if err := cursor.All(ctx, &gifts); err != nil {
+		return nil, err
+	}
+	return gifts, nil
+}
+
+// MarkGiftReversed flags a gift as reversed and returns it as it was before.
+// It returns mongo.ErrNoDocuments if the gift doesn't exist or was already
+// reversed, so a gift is only ever reversed once.
+func MarkGiftReversed(id primitive.ObjectID, reversedBy string) (*models.Gift, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	var gift models.Gift
+	err := GiftCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "reversed": false},
+		bson.M{"$set": bson.M{"reversed": true, "reversedAt": now, "reversedBy": reversedBy}},
+	).Decode(&gift)
+	if err != nil {
+		return nil, err
+	}
+	return &gift, nil
+}
@@ -32,9 +32,17 @@ type CatalogEntry struct {
 	ItemGrants           []map[string]interface{} `json:"itemGrants"`
 	SortPriority         int                      `json:"sortPriority"`
 	CatalogGroupPriority int                      `json:"catalogGroupPriority"`
+	DynamicBundleInfo    *DynamicBundleInfo       `json:"dynamicBundleInfo,omitempty"`
 }
 
+// GetItemShop builds the storefront catalog running now.
 func GetItemShop() map[string]interface{} {
+	return GetItemShopForDate(time.Now())
+}
+
+// GetItemShopForDate builds the storefront catalog running on day, from its
+// stored, scheduled or rotated shop.
+func GetItemShopForDate(day time.Time) map[string]interface{} {
 	baseDir := "."
 	catalogPath := filepath.Join(baseDir, "static", "responses", "catalog.json")
 	configPath := filepath.Join(baseDir, "static", "responses", "catalog_config.json")
@@ -46,9 +54,11 @@ func GetItemShop() map[string]interface{} {
 	var config map[string]map[string]interface{}
 	json.Unmarshal(catalogBytes, &catalog)
 	json.Unmarshal(configBytes, &config)
+	if rotation := ShopForDate(day); rotation != nil {
+		config = ShopRotationConfigEntries(rotation)
+	}
 
-	tomorrow := time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
-	saleExpiry := tomorrow.Add(-time.Minute).Format(time.RFC3339)
+	saleExpiry := ShopEnd(day).Add(-time.Minute).Format(time.RFC3339)
 
 	storefronts := catalog["storefronts"].([]interface{})
 
@@ -122,14 +132,21 @@ func GetItemShop() map[string]interface{} {
 		entry.DevName = ""
 		entry.OfferID = ""
 		entry.FulfillmentIds = []string{}
-		entry.DailyLimit = -1
-		entry.WeeklyLimit = -1
-		entry.MonthlyLimit = -1
+		entry.DailyLimit = configLimit(value["dailyLimit"])
+		entry.WeeklyLimit = configLimit(value["weeklyLimit"])
+		entry.MonthlyLimit = configLimit(value["monthlyLimit"])
 		entry.Categories = []string{}
+		// Offers are sold for V-Bucks unless they name a GameItem currency,
+		// e.g. event tokens.
+		currencyType, currencySubType := "MtxCurrency", ""
+		if configured, ok := value["currencyType"].(string); ok && configured != "" {
+			currencyType = configured
+			currencySubType, _ = value["currencySubType"].(string)
+		}
 		entry.Prices = []map[string]interface{}{
 			{
-				"currencyType":    "MtxCurrency",
-				"currencySubType": "",
+				"currencyType":    currencyType,
+				"currencySubType": currencySubType,
 				"regularPrice":    value["price"],
 				"finalPrice":      value["price"],
 				"saleExpiration":  saleExpiry,
@@ -152,6 +169,9 @@ func GetItemShop() map[string]interface{} {
 			"giftRecordIds":           []interface{}{},
 		}
 		entry.Refundable = true
+		if refundable, ok := value["refundable"].(bool); ok {
+			entry.Refundable = refundable
+		}
 		entry.MetaInfo = []map[string]string{
 			{"key": "SectionId", "value": "Featured"},
 			{"key": "TileSize", "value": "Small"},
@@ -172,11 +192,13 @@ func GetItemShop() map[string]interface{} {
 			entry.Categories = featuredAssignments[key]
 		}
 
+		var templateIDs []string
 		for _, grant := range itemGrants {
 			id, ok := grant.(string)
 			if !ok || id == "" {
 				continue
 			}
+			templateIDs = append(templateIDs, id)
 			entry.Requirements = append(entry.Requirements, map[string]interface{}{
 				"requirementType": "DenyOnItemOwnership",
 				"requiredId":      id,
@@ -188,8 +210,21 @@ func GetItemShop() map[string]interface{} {
 			})
 		}
 
+		// Bundles stay on sale until every item is owned and get cheaper
+		// for each one that is.
+		if len(templateIDs) > 1 {
+			itemPrices, _ := value["itemPrices"].(map[string]interface{})
+			entry.OfferType = "DynamicBundle"
+			entry.Requirements = []map[string]interface{}{}
+			entry.DynamicBundleInfo = newDynamicBundleInfo(templateIDs, toInt(value["price"]), currencyType, currencySubType, itemPrices, value["floorPrice"])
+		}
+
 		if len(entry.ItemGrants) > 0 {
-			keyHash := sha1.Sum([]byte(fmt.Sprintf("%v_%v", itemGrants, value["price"])))
+			offerKey := fmt.Sprintf("%v_%v", itemGrants, value["price"])
+			if currencyType != "MtxCurrency" {
+				offerKey += "_" + currencySubType
+			}
+			keyHash := sha1.Sum([]byte(offerKey))
 			uid := fmt.Sprintf("%x", keyHash)
 			entry.DevName = uid
 			entry.OfferID = uid
@@ -208,6 +243,22 @@ func GetItemShop() map[string]interface{} {
 	return catalog
 }
 
+// configLimit reads a purchase limit from a catalog_config entry; anything
+// but a positive number means unlimited (-1).
+func configLimit(v interface{}) int {
+	switch limit := v.(type) {
+	case int:
+		if limit > 0 {
+			return limit
+		}
+	case float64:
+		if limit > 0 {
+			return int(limit)
+		}
+	}
+	return -1
+}
+
 func GetOfferID(offerId string) (string, *CatalogEntry) {
 	catalog := GetItemShop()
 	storefronts, ok := catalog["storefronts"].([]interface{})
@@ -0,0 +1,203 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Failed password logins are counted per login name and per IP. Once a
+// counter passes its threshold every further failure locks the key for
+// LoginLockoutBase, doubling each time up to LoginLockoutMax. Counters are
+// kept in Mongo and forgotten after LoginFailureWindow without failures.
+var (
+	LoginAccountThreshold = 5
+	LoginIPThreshold      = 20
+	LoginLockoutBase      = 30 * time.Second
+	LoginLockoutMax       = time.Hour
+	LoginFailureWindow    = 15 * time.Minute
+)
+
+var LoginAttemptCollection *mongo.Collection
+
+// loginAttempts is the failure counter of one login name or IP. Mongo drops
+// it at expireAt, once it is out of the failure window and no longer locked.
+type loginAttempts struct {
+	Key         string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	LastFailure time.Time `bson:"lastFailure"`
+	LockedUntil time.Time `bson:"lockedUntil,omitempty"`
+	ExpireAt    time.Time `bson:"expireAt"`
+}
+
+// InitLoginGuard binds the login failure counters, so lockouts hold across
+// restarts and instances. It has to run after InitMongoDB.
+func InitLoginGuard() error {
+	LoginAttemptCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("login_attempts")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := LoginAttemptCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expireAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create login attempt indexes:", err)
+	}
+	return err
+}
+
+func accountLoginKey(login string) string {
+	return "account:" + strings.ToLower(strings.TrimSpace(login))
+}
+
+func ipLoginKey(ip string) string {
+	return "ip:" + ip
+}
+
+// LoginName is the name an account's failures are counted under: the email
+// it signs in with, so the password grant, the otp grant and the 2FA guarded
+// API all share one counter.
+func LoginName(user *models.User) string {
+	if mobileUser, err := FindMobileByAccountID(user.AccountID); err == nil && mobileUser != nil {
+		return mobileUser.Email
+	}
+	return user.Email
+}
+
+// LoginLockout returns how long logins for this login name or IP are still
+// locked, or zero if they are not.
+func LoginLockout(login, ip string) time.Duration {
+	if LoginAttemptCollection == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	cursor, err := LoginAttemptCollection.Find(ctx, bson.M{
+		"_id":         bson.M{"$in": []string{accountLoginKey(login), ipLoginKey(ip)}},
+		"lockedUntil": bson.M{"$gt": now},
+	})
+	if err != nil {
+		Error.Logf("Failed to read login lockouts of %s: %v", login, err)
+		return 0
+	}
+	var attempts []loginAttempts
+	if err := cursor.All(ctx, &attempts); err != nil {
+		Error.Logf("Failed to read login lockouts of %s: %v", login, err)
+		return 0
+	}
+
+	remaining := time.Duration(0)
+	for _, a := range attempts {
+		if d := a.LockedUntil.Sub(now); d > remaining {
+			remaining = d
+		}
+	}
+	return remaining
+}
+
+// RecordLoginFailure counts a failed login and returns the lockout that is
+// now in effect, if any.
+func RecordLoginFailure(login, ip string) time.Duration {
+	if LoginAttemptCollection == nil {
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	lockout := time.Duration(0)
+	for key, threshold := range map[string]int{accountLoginKey(login): LoginAccountThreshold, ipLoginKey(ip): LoginIPThreshold} {
+		// Counting restarts once the last failure is out of the window; the
+		// update does it in one step so parallel failures all count.
+		var a loginAttempts
+		err := LoginAttemptCollection.FindOneAndUpdate(ctx,
+			bson.M{"_id": key},
+			mongo.Pipeline{{{Key: "$set", Value: bson.M{
+				"failures": bson.M{"$cond": bson.A{
+					bson.M{"$gt": bson.A{"$lastFailure", now.Add(-LoginFailureWindow)}},
+					bson.M{"$add": bson.A{"$failures", 1}},
+					1,
+				}},
+				"lastFailure": now,
+				"expireAt":    bson.M{"$max": bson.A{now.Add(LoginFailureWindow), "$lockedUntil"}},
+			}}}},
+			options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+		).Decode(&a)
+		if err != nil {
+			Error.Logf("Failed to record login failure of %s: %v", key, err)
+			continue
+		}
+
+		if a.Failures < threshold {
+			continue
+		}
+		d := LoginLockoutMax
+		if shift := a.Failures - threshold; shift < 16 {
+			d = min(LoginLockoutBase<<uint(shift), LoginLockoutMax)
+		}
+		lockedUntil := now.Add(d)
+		_, err = LoginAttemptCollection.UpdateOne(ctx,
+			bson.M{"_id": key},
+			bson.M{"$max": bson.M{"lockedUntil": lockedUntil, "expireAt": lockedUntil}})
+		if err != nil {
+			Error.Logf("Failed to lock logins for %s: %v", key, err)
+		}
+		if d > lockout {
+			lockout = d
+		}
+		if a.Failures == threshold {
+			Backend.Logf("Locked logins for %s for %s after %d failed attempts", key, d, a.Failures)
+		}
+	}
+	return lockout
+}
+
+// RecordLoginSuccess forgets the failures of the login name. Call it only
+// once the whole login, second factor included, went through. The IP counter
+// is kept so one valid account can't be used to reset a stuffing run.
+func RecordLoginSuccess(login string) {
+	if LoginAttemptCollection == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := LoginAttemptCollection.DeleteOne(ctx, bson.M{"_id": accountLoginKey(login)}); err != nil {
+		Error.Logf("Failed to reset login failures of %s: %v", login, err)
+	}
+}
+
+// ClearLoginLockout removes the counters for a login name and, if given, an IP.
+func ClearLoginLockout(login, ip string) bool {
+	if LoginAttemptCollection == nil {
+		return false
+	}
+	keys := []string{}
+	if login != "" {
+		keys = append(keys, accountLoginKey(login))
+	}
+	if ip != "" {
+		keys = append(keys, ipLoginKey(ip))
+	}
+	if len(keys) == 0 {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	res, err := LoginAttemptCollection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": keys}})
+	if err != nil {
+		Error.Logf("Failed to clear login lockout of %s: %v", strings.Join(keys, ", "), err)
+		return false
+	}
+	return res.DeletedCount > 0
+}
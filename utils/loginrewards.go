@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+type LoginReward struct {
+	Day        int    `json:"day"`
+	TemplateID string `json:"templateId"`
+	Quantity   int    `json:"quantity"`
+}
+
+// LoginRewardCalendar is the daily login reward cycle. Day is 1-based and the
+// calendar starts over after its last day.
+type LoginRewardCalendar struct {
+	GiftBoxTemplateID string        `json:"giftBoxTemplateId"`
+	Rewards           []LoginReward `json:"rewards"`
+}
+
+var (
+	loginRewardsOnce sync.Once
+	loginRewards     *LoginRewardCalendar
+	loginRewardsErr  error
+)
+
+// LoadLoginRewards reads static/responses/LoginRewards.json, caching it after
+// the first read.
+func LoadLoginRewards() (*LoginRewardCalendar, error) {
+	loginRewardsOnce.Do(func() {
+		raw, err := os.ReadFile("./static/responses/LoginRewards.json")
+		if err != nil {
+			Error.Logf("Failed to read login rewards file: %v", err)
+			loginRewardsErr = err
+			return
+		}
+		var calendar LoginRewardCalendar
+		if err := json.Unmarshal(raw, &calendar); err != nil {
+			Error.Logf("Failed to parse login rewards: %v", err)
+			loginRewardsErr = err
+			return
+		}
+		if calendar.GiftBoxTemplateID == "" {
+			calendar.GiftBoxTemplateID = "GiftBox:gb_default"
+		}
+		loginRewards = &calendar
+	})
+	return loginRewards, loginRewardsErr
+}
+
+// Days is the length of the calendar.
+func (cal *LoginRewardCalendar) Days() int {
+	days := 0
+	for _, reward := range cal.Rewards {
+		days = max(days, reward.Day)
+	}
+	return days
+}
+
+// RewardsForDay returns the rewards of a calendar day keyed by templateId.
+func (cal *LoginRewardCalendar) RewardsForDay(day int) map[string]int {
+	rewards := map[string]int{}
+	for _, reward := range cal.Rewards {
+		if reward.Day == day && reward.Quantity > 0 {
+			rewards[reward.TemplateID] += reward.Quantity
+		}
+	}
+	return rewards
+}
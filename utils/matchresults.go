@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var MatchResultCollection *mongo.Collection
+
+var errMatchResultsUnavailable = errors.New("match results are unavailable")
+
+// InitMatchResults binds the record of applied match results. It has to run
+// after InitMongoDB.
+func InitMatchResults() error {
+	MatchResultCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("match_results")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := MatchResultCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "matchId", Value: 1}, {Key: "accountId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create match result indexes:", err)
+	}
+	return err
+}
+
+// ReserveMatchResult records that a match's results are being applied to
+// accountId. It returns false when they already were, so a server resending
+// its results can't award them twice.
+func ReserveMatchResult(matchId, accountId string) (bool, error) {
+	if MatchResultCollection == nil {
+		return false, errMatchResultsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := MatchResultCollection.InsertOne(ctx, bson.M{
+		"matchId":   matchId,
+		"accountId": accountId,
+		"applied":   time.Now().UTC(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReleaseMatchResult forgets a reservation whose results were not saved.
+func ReleaseMatchResult(matchId, accountId string) {
+	if MatchResultCollection == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := MatchResultCollection.DeleteOne(ctx, bson.M{"matchId": matchId, "accountId": accountId}); err != nil {
+		Error.Logf("Failed to release match %s results of %s: %v", matchId, accountId, err)
+	}
+}
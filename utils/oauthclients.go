@@ -0,0 +1,148 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"encoding/json"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	DefaultAccessTokenHours  = 8
+	DefaultRefreshTokenHours = 24
+	DefaultClientTokenHours  = 4
+)
+
+var OAuthClientCollection *mongo.Collection
+
+// oauthClientSeed is the shape of an OAUTH_CLIENTS entry. The secret is given
+// in plain text and only its hash is written to the database.
+type oauthClientSeed struct {
+	ClientID          string   `json:"clientId"`
+	Secret            string   `json:"secret"`
+	GrantTypes        []string `json:"grantTypes"`
+	Scopes            []string `json:"scopes"`
+	AccessTokenHours  int      `json:"accessTokenHours"`
+	RefreshTokenHours int      `json:"refreshTokenHours"`
+}
+
+var allGrantTypes = []string{
+	"client_credentials",
+	"password",
+	"refresh_token",
+	"exchange_code",
+	"authorization_code",
+	"device_auth",
+}
+
+var defaultScopes = []string{
+	"basic_profile",
+	"friends_list",
+	"presence",
+	"openid",
+	"account:device_auth",
+	"account:exchange",
+}
+
+// defaultOAuthClients are the public game clients, registered when
+// OAUTH_CLIENTS is not set and the collection is still empty.
+var defaultOAuthClients = []oauthClientSeed{
+	{ClientID: "ec684b8c687f479fadea3cb2ad83f5c6", Secret: "e1f31c211f28413186262d37a13fc84d"},
+	{ClientID: "3446cd72694c4a4485d81b77adbb2141", Secret: "9209d4a5e25a457fb9b07489d313b41a"},
+	{ClientID: "34a02cf8f4414e29b15921876da36f9a", Secret: "daafbccc737745039dffe53d94fc76cf"},
+}
+
+// InitOAuthClients upserts the clients listed in OAUTH_CLIENTS (a JSON array)
+// into the oauth_clients collection. It has to run after InitMongoDB.
+func InitOAuthClients() error {
+	OAuthClientCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("oauth_clients")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := OAuthClientCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "clientId", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create oauth client index:", err)
+		return err
+	}
+
+	var seeds []oauthClientSeed
+	if raw := os.Getenv("OAUTH_CLIENTS"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &seeds); err != nil {
+			Error.Log("Invalid OAUTH_CLIENTS in env: ", err)
+			return err
+		}
+	} else {
+		count, err := OAuthClientCollection.CountDocuments(ctx, bson.M{})
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		seeds = defaultOAuthClients
+	}
+
+	for _, seed := range seeds {
+		if seed.ClientID == "" || seed.Secret == "" {
+			continue
+		}
+		secretHash, err := HashSecret(seed.Secret)
+		if err != nil {
+			return err
+		}
+		client := models.OAuthClient{
+			ClientID:          seed.ClientID,
+			SecretHash:        secretHash,
+			GrantTypes:        seed.GrantTypes,
+			Scopes:            seed.Scopes,
+			AccessTokenHours:  seed.AccessTokenHours,
+			RefreshTokenHours: seed.RefreshTokenHours,
+		}
+		if len(client.GrantTypes) == 0 {
+			client.GrantTypes = allGrantTypes
+		}
+		if client.Scopes == nil {
+			client.Scopes = defaultScopes
+		}
+		_, err = OAuthClientCollection.UpdateOne(ctx,
+			bson.M{"clientId": client.ClientID},
+			bson.M{"$set": client},
+			options.Update().SetUpsert(true))
+		if err != nil {
+			MongoDB.Log("Failed to register oauth client", client.ClientID, err)
+			return err
+		}
+	}
+	return nil
+}
+
+func FindOAuthClient(clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := OAuthClientCollection.FindOne(context.TODO(), bson.M{"clientId": clientID}).Decode(&client)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// AuthenticateOAuthClient returns the registered client matching the given
+// credentials, or nil when the id is unknown or the secret is wrong.
+func AuthenticateOAuthClient(clientID, secret string) *models.OAuthClient {
+	if OAuthClientCollection == nil {
+		return nil
+	}
+	client, err := FindOAuthClient(clientID)
+	if err != nil || !VerifyPassword(secret, client.SecretHash) {
+		return nil
+	}
+	return client
+}
@@ -0,0 +1,270 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var PaymentCollection *mongo.Collection
+
+var errPaymentsUnavailable = errors.New("payments are unavailable")
+
+// InitPayments binds the real-money orders collection. It has to run after
+// InitMongoDB.
+func InitPayments() error {
+	PaymentCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("payments")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := PaymentCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "orderId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "accountId", Value: 1}, {Key: "created", Value: -1}}},
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create payment indexes:", err)
+	}
+	return err
+}
+
+// MtxPackQuantity is how many V-Bucks a RealMoney offer credits, its
+// MtxQuantity plus MtxBonus meta.
+func MtxPackQuantity(entry *CatalogEntry) int {
+	quantity := 0
+	for _, key := range []string{"MtxQuantity", "MtxBonus"} {
+		switch v := entry.Meta[key].(type) {
+		case string:
+			n, _ := strconv.Atoi(v)
+			quantity += n
+		default:
+			quantity += toInt(v)
+		}
+	}
+	return quantity
+}
+
+// OfferAppStoreID is the first store SKU of a RealMoney offer.
+func OfferAppStoreID(entry *CatalogEntry) string {
+	for _, id := range entry.AppStoreId {
+		if id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// CreatePayment places a pending order for a RealMoney offer.
+func CreatePayment(accountId string, entry *CatalogEntry, quantity int) (*models.Payment, error) {
+	if PaymentCollection == nil {
+		return nil, errPaymentsUnavailable
+	}
+	payment := &models.Payment{
+		OrderID:     GenerateRandomID(),
+		AccountID:   accountId,
+		OfferID:     entry.OfferID,
+		AppStoreID:  OfferAppStoreID(entry),
+		MtxQuantity: MtxPackQuantity(entry),
+		Quantity:    quantity,
+		Status:      models.PaymentPending,
+		Created:     time.Now().UTC(),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := PaymentCollection.InsertOne(ctx, payment); err != nil {
+		return nil, err
+	}
+	return payment, nil
+}
+
+// FindPayment returns the order with orderId, or nil when there is none.
+func FindPayment(orderId string) (*models.Payment, error) {
+	if PaymentCollection == nil {
+		return nil, errPaymentsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var payment models.Payment
+	err := PaymentCollection.FindOne(ctx, bson.M{"orderId": orderId}).Decode(&payment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+// SettlePayment moves a pending order to status. Orders that were already
+// settled are returned unchanged, so callers compare the status they get.
+func SettlePayment(orderId string, status models.PaymentStatus) (*models.Payment, error) {
+	if PaymentCollection == nil {
+		return nil, errPaymentsUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var payment models.Payment
+	err := PaymentCollection.FindOneAndUpdate(ctx,
+		bson.M{"orderId": orderId, "status": models.PaymentPending},
+		bson.M{"$set": bson.M{"status": status, "settled": time.Now().UTC()}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&payment)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return FindPayment(orderId)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A failed order no longer holds its place in the offer's limits.
+	if payment.Status == models.PaymentFailed {
+		// Orders placed before the quantity was stored were for one.
+		ReleaseOfferPurchase(payment.AccountID, payment.OfferID, payment.Created, max(payment.Quantity, 1))
+	}
+	return &payment, nil
+}
+
+// FulfillPayment credits a completed order's V-Bucks to common_core. Orders
+// are recorded in in_app_purchases, so fulfilling one again changes
+// nothing; it reports whether this call credited it.
+func FulfillPayment(payment *models.Payment) (bool, error) {
+	if payment.Status != models.PaymentCompleted {
+		return false, errors.New("payment " + payment.OrderID + " is " + string(payment.Status))
+	}
+
+	credited := false
+	_, err := UpdateProfiles(payment.AccountID, func(doc *models.Profiles) ([]string, error) {
+		credited = false
+		commonCore, ok := doc.Profiles["common_core"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("common_core profile not found")
+		}
+		if !creditRealMoneyPurchase(commonCore, payment) {
+			return nil, nil
+		}
+		credited = true
+		changed := []string{"common_core"}
+		if profile0, ok := doc.Profiles["profile0"].(map[string]interface{}); ok {
+			addMtxPurchased(profile0, payment.MtxQuantity)
+			changed = append(changed, "profile0")
+		}
+		return changed, nil
+	})
+	if err != nil || !credited {
+		return false, err
+	}
+
+	RecordReceipt(models.Receipt{
+		ReceiptID:    payment.OrderID,
+		AccountID:    payment.AccountID,
+		Type:         models.ReceiptRealMoney,
+		OfferID:      payment.OfferID,
+		CurrencyType: "MtxCurrency",
+		Amount:       payment.MtxQuantity,
+		Quantity:     1,
+		Note:         payment.AppStoreID,
+	})
+	return true, nil
+}
+
+// RealMoneyReceipt is how in_app_purchases names an order.
+func RealMoneyReceipt(orderId string) string {
+	return "EPIC:" + orderId
+}
+
+// HasRealMoneyPurchase reports whether common_core was credited for orderId.
+func HasRealMoneyPurchase(commonCore map[string]interface{}, orderId string) bool {
+	purchases := inAppPurchases(commonCore)
+	for _, receipt := range realMoneyReceipts(purchases) {
+		if receipt == RealMoneyReceipt(orderId) {
+			return true
+		}
+	}
+	return false
+}
+
+func creditRealMoneyPurchase(commonCore map[string]interface{}, payment *models.Payment) bool {
+	if HasRealMoneyPurchase(commonCore, payment.OrderID) {
+		return false
+	}
+	purchases := inAppPurchases(commonCore)
+	receipts := realMoneyReceipts(purchases)
+	purchases["receipts"] = append(receipts, RealMoneyReceipt(payment.OrderID))
+
+	counts, ok := purchases["fulfillmentCounts"].(map[string]interface{})
+	if !ok {
+		counts = map[string]interface{}{}
+		purchases["fulfillmentCounts"] = counts
+	}
+	counts[payment.OfferID] = toInt(counts[payment.OfferID]) + 1
+
+	addMtxPurchased(commonCore, payment.MtxQuantity)
+	return true
+}
+
+func inAppPurchases(commonCore map[string]interface{}) map[string]interface{} {
+	stats, ok := commonCore["stats"].(map[string]interface{})
+	if !ok {
+		stats = map[string]interface{}{}
+		commonCore["stats"] = stats
+	}
+	attributes, ok := stats["attributes"].(map[string]interface{})
+	if !ok {
+		attributes = map[string]interface{}{}
+		stats["attributes"] = attributes
+	}
+	purchases, ok := attributes["in_app_purchases"].(map[string]interface{})
+	if !ok {
+		purchases = map[string]interface{}{}
+		attributes["in_app_purchases"] = purchases
+	}
+	return purchases
+}
+
+func realMoneyReceipts(purchases map[string]interface{}) []interface{} {
+	switch receipts := purchases["receipts"].(type) {
+	case []interface{}:
+		return receipts
+	case primitive.A:
+		return []interface{}(receipts)
+	}
+	return []interface{}{}
+}
+
+// addMtxPurchased adds quantity V-Bucks to a profile's Currency:MtxPurchased
+// item, creating it when missing.
+func addMtxPurchased(profile map[string]interface{}, quantity int) {
+	items, ok := profile["items"].(map[string]interface{})
+	if !ok {
+		items = map[string]interface{}{}
+		profile["items"] = items
+	}
+	for _, v := range items {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if templateID, _ := item["templateId"].(string); strings.EqualFold(templateID, "Currency:MtxPurchased") {
+			item["quantity"] = toInt(item["quantity"]) + quantity
+			return
+		}
+	}
+	items["Currency:MtxPurchased"] = map[string]interface{}{
+		"templateId": "Currency:MtxPurchased",
+		"attributes": map[string]interface{}{"platform": "EpicPC"},
+		"quantity":   quantity,
+	}
+}
@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// ProfileWriteAttempts is how often a profile update is retried after losing
+// a revision race before giving up with ErrProfileRevisionMismatch.
+const ProfileWriteAttempts = 3
+
+var ErrProfileRevisionMismatch = errors.New("profile revision mismatch")
+
+// ProfileRevision is the rvn/commandRevision pair a profile was read with.
+type ProfileRevision struct {
+	Rvn             int
+	CommandRevision int
+}
+
+// ProfileRevisions snapshots the revision of every profile in the document.
+func ProfileRevisions(doc *models.Profiles) map[string]ProfileRevision {
+	revs := make(map[string]ProfileRevision, len(doc.Profiles))
+	for id, raw := range doc.Profiles {
+		profile, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		revs[id] = ProfileRevision{
+			Rvn:             toInt(profile["rvn"]),
+			CommandRevision: toInt(profile["commandRevision"]),
+		}
+	}
+	return revs
+}
+
+// revisionMatch matches a stored revision, treating a missing field as 0.
+func revisionMatch(rev int) interface{} {
+	if rev == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return rev
+}
+
+// SaveProfiles applies set to the account's profiles document only if every
+// profile it touches still has the revision it was read with. set uses the
+// same keys as a $set, e.g. "profiles.athena" or "profiles".
+func SaveProfiles(ctx context.Context, accountID string, revs map[string]ProfileRevision, set bson.M) error {
+	touched := map[string]bool{}
+	for key := range set {
+		if key == "profiles" {
+			for id := range revs {
+				touched[id] = true
+			}
+			continue
+		}
+		if rest, ok := strings.CutPrefix(key, "profiles."); ok {
+			touched[strings.SplitN(rest, ".", 2)[0]] = true
+		}
+	}
+
+	filter := bson.M{"accountId": accountID}
+	for id := range touched {
+		rev := revs[id]
+		filter["profiles."+id+".rvn"] = revisionMatch(rev.Rvn)
+		filter["profiles."+id+".commandRevision"] = revisionMatch(rev.CommandRevision)
+	}
+
+	result, err := ProfileCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrProfileRevisionMismatch
+	}
+	return nil
+}
+
+// BumpProfileRevision marks a profile as changed by one command.
+func BumpProfileRevision(profile map[string]interface{}) {
+	profile["rvn"] = toInt(profile["rvn"]) + 1
+	profile["commandRevision"] = toInt(profile["commandRevision"]) + 1
+	profile["updated"] = time.Now().UTC().Format(time.RFC3339)
+}
+
+// UpdateProfiles loads the account's profiles, lets mutate change them and
+// writes back the profiles it reports as changed, bumping their revisions.
+// Lost races are retried on a fresh copy.
+func UpdateProfiles(accountID string, mutate func(doc *models.Profiles) ([]string, error)) (*models.Profiles, error) {
+	for attempt := 0; attempt < ProfileWriteAttempts; attempt++ {
+		doc, err := FindProfileByAccountID(accountID)
+		if err != nil {
+			return nil, err
+		}
+		revs := ProfileRevisions(doc)
+
+		changed, err := mutate(doc)
+		if err != nil {
+			return doc, err
+		}
+		if len(changed) == 0 {
+			return doc, nil
+		}
+
+		set := bson.M{}
+		for _, id := range changed {
+			profile, ok := doc.Profiles[id].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			BumpProfileRevision(profile)
+			set["profiles."+id] = profile
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		err = SaveProfiles(ctx, accountID, revs, set)
+		cancel()
+		if err == nil {
+			return doc, nil
+		}
+		if !errors.Is(err, ErrProfileRevisionMismatch) {
+			return doc, err
+		}
+	}
+	return nil, ErrProfileRevisionMismatch
+}
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var PurchaseLimitCollection *mongo.Collection
+
+// InitPurchaseLimits binds the purchase limit counters. It has to run after
+// InitMongoDB.
+func InitPurchaseLimits() error {
+	PurchaseLimitCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("purchase_limits")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := PurchaseLimitCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expireAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create purchase limit indexes:", err)
+	}
+	return err
+}
+
+// OfferPurchases is how many times an account bought an offer in the
+// current limit windows.
+type OfferPurchases struct {
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// PurchaseLimitWindows returns when the daily, weekly and monthly limit
+// windows containing now started. Weeks start on Monday, all in UTC.
+func PurchaseLimitWindows(now time.Time) (daily, weekly, monthly time.Time) {
+	daily = now.UTC().Truncate(24 * time.Hour)
+	weekly = daily.AddDate(0, 0, -((int(daily.Weekday()) + 6) % 7))
+	monthly = time.Date(daily.Year(), daily.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return daily, weekly, monthly
+}
+
+// purchaseLimitWindow is one limit window of an offer and the id of the
+// counter that holds what the account bought in it.
+type purchaseLimitWindow struct {
+	counterID string
+	limit     int
+	bought    int
+	end       time.Time
+}
+
+func purchaseLimitWindows(accountId, offerId string, at time.Time) []purchaseLimitWindow {
+	daily, weekly, monthly := PurchaseLimitWindows(at)
+	id := func(kind string, start time.Time) string {
+		return accountId + ":" + offerId + ":" + kind + ":" + start.Format("2006-01-02")
+	}
+	return []purchaseLimitWindow{
+		{counterID: id("daily", daily), end: daily.AddDate(0, 0, 1)},
+		{counterID: id("weekly", weekly), end: weekly.AddDate(0, 0, 7)},
+		{counterID: id("monthly", monthly), end: monthly.AddDate(0, 1, 0)},
+	}
+}
+
+// PurchaseLimitSlot is a reservation of purchases against an offer's limit
+// windows.
+type PurchaseLimitSlot struct {
+	counterIDs []string
+	quantity   int
+}
+
+// ReservePurchases takes quantity purchases of entry from the account's
+// daily, weekly and monthly limits. Each window has a counter, started from
+// the purchase receipts, that is only raised while it stays within the
+// limit, so parallel purchases can't both take the last one. It returns
+// false when a limit would be exceeded; offers without limits always fit.
+func ReservePurchases(accountId string, entry *CatalogEntry, quantity int, now time.Time) (*PurchaseLimitSlot, bool, error) {
+	slot := &PurchaseLimitSlot{quantity: quantity}
+	if PurchaseLimitCollection == nil || (entry.DailyLimit < 0 && entry.WeeklyLimit < 0 && entry.MonthlyLimit < 0) {
+		return slot, true, nil
+	}
+
+	counts, err := CountOfferPurchases(accountId, now)
+	if err != nil {
+		return nil, false, err
+	}
+	bought := counts[entry.OfferID]
+
+	windows := purchaseLimitWindows(accountId, entry.OfferID, now)
+	windows[0].limit, windows[0].bought = entry.DailyLimit, bought.Daily
+	windows[1].limit, windows[1].bought = entry.WeeklyLimit, bought.Weekly
+	windows[2].limit, windows[2].bought = entry.MonthlyLimit, bought.Monthly
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for _, window := range windows {
+		if window.limit < 0 {
+			continue
+		}
+		// The first purchase in a window starts its counter from the
+		// receipts; later ones only trust the counter.
+		_, err := PurchaseLimitCollection.UpdateOne(ctx,
+			bson.M{"_id": window.counterID},
+			bson.M{"$setOnInsert": bson.M{"count": window.bought, "expireAt": window.end.Add(24 * time.Hour)}},
+			options.Update().SetUpsert(true))
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			ReleasePurchases(slot)
+			return nil, false, err
+		}
+
+		res, err := PurchaseLimitCollection.UpdateOne(ctx,
+			bson.M{"_id": window.counterID, "count": bson.M{"$lte": window.limit - quantity}},
+			bson.M{"$inc": bson.M{"count": quantity}})
+		if err != nil || res.MatchedCount == 0 {
+			ReleasePurchases(slot)
+			return nil, false, err
+		}
+		slot.counterIDs = append(slot.counterIDs, window.counterID)
+	}
+	return slot, true, nil
+}
+
+// ReleasePurchases hands back a reservation for a purchase that didn't go
+// through.
+func ReleasePurchases(slot *PurchaseLimitSlot) {
+	if slot == nil || len(slot.counterIDs) == 0 {
+		return
+	}
+	releasePurchaseCounters(slot.counterIDs, slot.quantity)
+	slot.counterIDs = nil
+}
+
+// ReleaseOfferPurchase gives a refunded or failed purchase made at
+// purchasedAt back to the offer's limit windows it was counted in.
+func ReleaseOfferPurchase(accountId, offerId string, purchasedAt time.Time, quantity int) {
+	if PurchaseLimitCollection == nil {
+		return
+	}
+	ids := []string{}
+	for _, window := range purchaseLimitWindows(accountId, offerId, purchasedAt) {
+		ids = append(ids, window.counterID)
+	}
+	releasePurchaseCounters(ids, quantity)
+}
+
+func releasePurchaseCounters(counterIDs []string, quantity int) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := PurchaseLimitCollection.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": counterIDs}, "count": bson.M{"$gte": quantity}},
+		bson.M{"$inc": bson.M{"count": -quantity}})
+	if err != nil {
+		Error.Logf("Failed to release %d purchases from %v: %v", quantity, counterIDs, err)
+	}
+}
+
+// CountOfferPurchases counts an account's purchases per offer in the limit
+// windows containing now, from its purchase receipts. Refunded purchases
+// don't count.
+func CountOfferPurchases(accountId string, now time.Time) (map[string]OfferPurchases, error) {
+	counts := map[string]OfferPurchases{}
+	if ReceiptCollection == nil {
+		return counts, nil
+	}
+
+	daily, weekly, monthly := PurchaseLimitWindows(now)
+	since := weekly
+	if monthly.Before(since) {
+		since = monthly
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	cursor, err := ReceiptCollection.Find(ctx, bson.M{
+		"accountId": accountId,
+		"type":      bson.M{"$in": []models.ReceiptType{models.ReceiptPurchase, models.ReceiptBattlePass, models.ReceiptRealMoney}},
+		"created":   bson.M{"$gte": since},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var purchases []models.Receipt
+	if err := cursor.All(ctx, &purchases); err != nil {
+		return nil, err
+	}
+	if len(purchases) == 0 {
+		return counts, nil
+	}
+
+	receiptIds := make([]string, 0, len(purchases))
+	for _, purchase := range purchases {
+		receiptIds = append(receiptIds, purchase.ReceiptID)
+	}
+	refunded := map[string]bool{}
+	cursor, err = ReceiptCollection.Find(ctx, bson.M{
+		"accountId":        accountId,
+		"type":             models.ReceiptRefund,
+		"relatedReceiptId": bson.M{"$in": receiptIds},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var refunds []models.Receipt
+	if err := cursor.All(ctx, &refunds); err != nil {
+		return nil, err
+	}
+	for _, refund := range refunds {
+		refunded[refund.RelatedReceiptID] = true
+	}
+
+	for _, purchase := range purchases {
+		if refunded[purchase.ReceiptID] {
+			continue
+		}
+		quantity := max(purchase.Quantity, 1)
+		count := counts[purchase.OfferID]
+		if !purchase.Created.Before(daily) {
+			count.Daily += quantity
+		}
+		if !purchase.Created.Before(weekly) {
+			count.Weekly += quantity
+		}
+		if !purchase.Created.Before(monthly) {
+			count.Monthly += quantity
+		}
+		counts[purchase.OfferID] = count
+	}
+	return counts, nil
+}
+
+// RemainingPurchases is how many more times the offer can be bought in the
+// current windows, or -1 when it has no limits.
+func RemainingPurchases(entry *CatalogEntry, bought OfferPurchases) int {
+	remaining := -1
+	for _, window := range []struct{ limit, bought int }{
+		{entry.DailyLimit, bought.Daily},
+		{entry.WeeklyLimit, bought.Weekly},
+		{entry.MonthlyLimit, bought.Monthly},
+	} {
+		if window.limit < 0 {
+			continue
+		}
+		left := max(window.limit-window.bought, 0)
+		if remaining < 0 || left < remaining {
+			remaining = left
+		}
+	}
+	return remaining
+}
+
+// ApplyPurchaseLimits tells the client how many more times the account can
+// buy each limited offer in catalog, through the offer's meta.
+func ApplyPurchaseLimits(catalog map[string]interface{}, accountId string) error {
+	counts, err := CountOfferPurchases(accountId, time.Now())
+	if err != nil {
+		return err
+	}
+	storefronts, _ := catalog["storefronts"].([]interface{})
+	for _, sf := range storefronts {
+		store, ok := sf.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		entries, _ := store["catalogEntries"].([]interface{})
+		for i, e := range entries {
+			entry, ok := e.(CatalogEntry)
+			if !ok {
+				continue
+			}
+			remaining := RemainingPurchases(&entry, counts[entry.OfferID])
+			if remaining < 0 {
+				continue
+			}
+			entry.Meta["PurchasesRemaining"] = remaining
+			entry.MetaInfo = append(entry.MetaInfo, map[string]string{
+				"key":   "PurchasesRemaining",
+				"value": strconv.Itoa(remaining),
+			})
+			entries[i] = entry
+		}
+	}
+	return nil
+}
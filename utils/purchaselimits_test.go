@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPurchaseLimitWindows(t *testing.T) {
+	// A Wednesday afternoon.
+	now := time.Date(2026, time.October, 14, 15, 30, 0, 0, time.UTC)
+	daily, weekly, monthly := PurchaseLimitWindows(now)
+
+	if want := time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC); !daily.Equal(want) {
+		t.Errorf("daily = %s, want %s", daily, want)
+	}
+	if want := time.Date(2026, time.October, 12, 0, 0, 0, 0, time.UTC); !weekly.Equal(want) {
+		t.Errorf("weekly = %s, want the Monday %s", weekly, want)
+	}
+	if want := time.Date(2026, time.October, 1, 0, 0, 0, 0, time.UTC); !monthly.Equal(want) {
+		t.Errorf("monthly = %s, want %s", monthly, want)
+	}
+}
+
+func TestPurchaseLimitWindowsOnSunday(t *testing.T) {
+	now := time.Date(2026, time.November, 1, 23, 59, 0, 0, time.UTC)
+	_, weekly, monthly := PurchaseLimitWindows(now)
+
+	if want := time.Date(2026, time.October, 26, 0, 0, 0, 0, time.UTC); !weekly.Equal(want) {
+		t.Errorf("weekly = %s, want %s", weekly, want)
+	}
+	if want := time.Date(2026, time.November, 1, 0, 0, 0, 0, time.UTC); !monthly.Equal(want) {
+		t.Errorf("monthly = %s, want %s", monthly, want)
+	}
+}
+
+func TestPurchaseLimitWindowsUseUTC(t *testing.T) {
+	tz := time.FixedZone("UTC+10", 10*60*60)
+	now := time.Date(2026, time.October, 15, 5, 0, 0, 0, tz)
+	daily, _, _ := PurchaseLimitWindows(now)
+
+	if want := time.Date(2026, time.October, 14, 0, 0, 0, 0, time.UTC); !daily.Equal(want) {
+		t.Errorf("daily = %s, want %s", daily, want)
+	}
+}
+
+func TestRemainingPurchases(t *testing.T) {
+	tests := []struct {
+		name                   string
+		daily, weekly, monthly int
+		bought                 OfferPurchases
+		want                   int
+	}{
+		{"unlimited", -1, -1, -1, OfferPurchases{Daily: 7}, -1},
+		{"daily only", 2, -1, -1, OfferPurchases{Daily: 1, Weekly: 5}, 1},
+		{"tightest window wins", 3, 5, -1, OfferPurchases{Daily: 0, Weekly: 4}, 1},
+		{"used up", 1, -1, 10, OfferPurchases{Daily: 1, Monthly: 1}, 0},
+		{"over the limit", -1, -1, 2, OfferPurchases{Monthly: 5}, 0},
+	}
+	for _, tt := range tests {
+		entry := &CatalogEntry{DailyLimit: tt.daily, WeeklyLimit: tt.weekly, MonthlyLimit: tt.monthly}
+		if got := RemainingPurchases(entry, tt.bought); got != tt.want {
+			t.Errorf("%s: RemainingPurchases = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPurchaseLimitCounterIDs(t *testing.T) {
+	at := time.Date(2026, time.October, 14, 15, 30, 0, 0, time.UTC)
+	windows := purchaseLimitWindows("acc", "offer", at)
+
+	want := []string{
+		"acc:offer:daily:2026-10-14",
+		"acc:offer:weekly:2026-10-12",
+		"acc:offer:monthly:2026-10-01",
+	}
+	for i, window := range windows {
+		if window.counterID != want[i] {
+			t.Errorf("counter %d = %s, want %s", i, window.counterID, want[i])
+		}
+		if !window.end.After(at) {
+			t.Errorf("counter %s ends at %s, before %s", window.counterID, window.end, at)
+		}
+	}
+}
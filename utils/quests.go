@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type QuestObjective struct {
+	BackendName string `json:"backendName"`
+	Count       int    `json:"count"`
+}
+
+type QuestDefinition struct {
+	TemplateID string           `json:"templateId"`
+	Objectives []QuestObjective `json:"objectives"`
+	XPReward   int              `json:"xpReward"`
+}
+
+// QuestData is one season's quest definitions.
+type QuestData struct {
+	MaxDailyQuests int               `json:"maxDailyQuests"`
+	DailyRerolls   int               `json:"dailyRerolls"`
+	Daily          []QuestDefinition `json:"daily"`
+}
+
+var (
+	questDataMu sync.Mutex
+	questData   = map[int]*QuestData{}
+)
+
+// LoadQuests reads the season's quest definitions from
+// static/responses/Quests, caching them after the first read.
+func LoadQuests(season int) (*QuestData, error) {
+	questDataMu.Lock()
+	defer questDataMu.Unlock()
+
+	if data, ok := questData[season]; ok {
+		return data, nil
+	}
+
+	file := "./static/responses/Quests/Season" + strconv.Itoa(season) + ".json"
+	raw, err := os.ReadFile(file)
+	if err != nil {
+		Error.Logf("Failed to read quests file: %v", err)
+		return nil, err
+	}
+	var data QuestData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		Error.Logf("Failed to parse quests: %v", err)
+		return nil, err
+	}
+	questData[season] = &data
+	return &data, nil
+}
+
+// CurrentQuests loads the quest definitions for the SEASON the backend runs.
+func CurrentQuests() (*QuestData, error) {
+	season, _ := strconv.Atoi(os.Getenv("SEASON"))
+	return LoadQuests(season)
+}
+
+// FindDaily returns the daily quest with templateID.
+func (q *QuestData) FindDaily(templateID string) (*QuestDefinition, bool) {
+	for i := range q.Daily {
+		if strings.EqualFold(q.Daily[i].TemplateID, templateID) {
+			return &q.Daily[i], true
+		}
+	}
+	return nil, false
+}
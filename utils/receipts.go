@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ReceiptCollection *mongo.Collection
+
+// InitReceipts binds the receipts collection. It has to run after InitMongoDB.
+func InitReceipts() error {
+	ReceiptCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("receipts")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := ReceiptCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "receiptId", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "accountId", Value: 1}, {Key: "created", Value: 1}}},
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create receipt indexes:", err)
+	}
+	return err
+}
+
+// RecordReceipt stores a receipt, giving it a receipt id if it has none.
+// Failures are logged, the change it describes has already been saved.
+func RecordReceipt(receipt models.Receipt) {
+	if ReceiptCollection == nil {
+		return
+	}
+	if receipt.ReceiptID == "" {
+		receipt.ReceiptID = GenerateRandomID()
+	}
+	if receipt.Created.IsZero() {
+		receipt.Created = time.Now().UTC()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := ReceiptCollection.InsertOne(ctx, receipt); err != nil {
+		Error.Logf("Failed to record %s receipt %s for %s: %v", receipt.Type, receipt.ReceiptID, receipt.AccountID, err)
+	}
+}
+
+// FindReceipt returns one of an account's receipts by its receipt id.
+func FindReceipt(accountId, receiptId string) (*models.Receipt, error) {
+	if ReceiptCollection == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var receipt models.Receipt
+	err := ReceiptCollection.FindOne(ctx, bson.M{"accountId": accountId, "receiptId": receiptId}).Decode(&receipt)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
+
+// FindReceipts returns an account's receipts, oldest first.
+func FindReceipts(accountId string) ([]models.Receipt, error) {
+	receipts := []models.Receipt{}
+	if ReceiptCollection == nil {
+		return receipts, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created", Value: 1}})
+	cursor, err := ReceiptCollection.Find(ctx, bson.M{"accountId": accountId}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
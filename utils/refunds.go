@@ -0,0 +1,21 @@
+package utils
+
+import "time"
+
+// RefundWindow is how long after a purchase it can be refunded, read from
+// REFUND_WINDOW_DAYS, 30 days by default.
+func RefundWindow() time.Duration {
+	return time.Duration(envInt("REFUND_WINDOW_DAYS", 30)) * 24 * time.Hour
+}
+
+// RefundTickets is how many refunds an account can have in use at once,
+// read from REFUND_TICKETS, 3 by default.
+func RefundTickets() int {
+	return envInt("REFUND_TICKETS", 3)
+}
+
+// RefundTicketRestore is how long a used refund ticket takes to come back,
+// read from REFUND_TICKET_RESTORE_DAYS, 365 days by default.
+func RefundTicketRestore() time.Duration {
+	return time.Duration(envInt("REFUND_TICKET_RESTORE_DAYS", 365)) * 24 * time.Hour
+}
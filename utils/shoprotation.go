@@ -0,0 +1,483 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShopRotationConfig drives the daily item shop rotation. It is read from
+// static/responses/shop_rotation.json.
+type ShopRotationConfig struct {
+	Enabled       bool     `json:"enabled"`
+	DailySlots    int      `json:"dailySlots"`
+	FeaturedSlots int      `json:"featuredSlots"`
+	NoRepeatDays  int      `json:"noRepeatDays"`
+	PoolTypes     []string `json:"poolTypes"`
+	FeaturedTypes []string `json:"featuredTypes"`
+	Exclude       []string `json:"exclude"`
+	// DefaultRarity is the rarity of a template type's items that have no
+	// entry in Rarities.
+	DefaultRarity map[string]string         `json:"defaultRarity"`
+	Rarities      map[string]string         `json:"rarities"`
+	RarityWeights map[string]int            `json:"rarityWeights"`
+	Prices        map[string]map[string]int `json:"prices"`
+}
+
+var ShopRotationCollection *mongo.Collection
+
+const (
+	shopDateLayout = "2006-01-02"
+	// shopRotationCacheTTL bounds how long a shop scheduled through another
+	// instance takes to show up.
+	shopRotationCacheTTL = time.Minute
+)
+
+var (
+	shopRotationMu       sync.Mutex
+	shopRotationCached   *models.ShopRotation
+	shopRotationCachedAt time.Time
+
+	shopPoolOnce sync.Once
+	shopPool     []string
+)
+
+// InitShopRotations binds the shop rotation collection. It has to run after
+// InitMongoDB.
+func InitShopRotations() error {
+	ShopRotationCollection = MongoClient.Database(os.Getenv("DB_NAME")).Collection("shoprotations")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := ShopRotationCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "date", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		MongoDB.Log("Failed to create shop rotation indexes:", err)
+	}
+	return err
+}
+
+// LoadShopRotationConfig reads the rotation config on every call so edits
+// apply to the next rotation without a restart.
+func LoadShopRotationConfig() (*ShopRotationConfig, error) {
+	raw, err := os.ReadFile("./static/responses/shop_rotation.json")
+	if err != nil {
+		return nil, err
+	}
+	var config ShopRotationConfig
+	if err := json.Unmarshal(raw, &config); err != nil {
+		Error.Logf("Failed to parse shop rotation config: %v", err)
+		return nil, err
+	}
+	return &config, nil
+}
+
+// ShopForDate returns the shop that runs on day: the stored or scheduled
+// shop covering it, or a freshly drawn rotation. Today's rotation is stored
+// on its first request; other days are only previewed. It returns nil when
+// nothing covers the day and rotation is disabled, so the shop falls back to
+// catalog_config.json.
+func ShopForDate(day time.Time) *models.ShopRotation {
+	day = day.UTC().Truncate(24 * time.Hour)
+	date := day.Format(shopDateLayout)
+	today := time.Now().UTC().Format(shopDateLayout)
+
+	shopRotationMu.Lock()
+	defer shopRotationMu.Unlock()
+	if date == today && shopRotationCached != nil && shopRotationCached.Date <= date &&
+		time.Since(shopRotationCachedAt) < shopRotationCacheTTL {
+		return shopRotationCached
+	}
+
+	rotation, err := findShopRotation(date)
+	if err != nil {
+		config, configErr := LoadShopRotationConfig()
+		if configErr != nil || !config.Enabled || date < today {
+			return nil
+		}
+		rotation = GenerateShopRotation(config, date, recentShopTemplates(date, config.NoRepeatDays))
+		if date != today {
+			return rotation
+		}
+		if err := storeShopRotation(rotation); err != nil {
+			// Another instance stored the day first; serve theirs.
+			if stored, findErr := findShopRotation(date); findErr == nil {
+				rotation = stored
+			}
+		}
+	}
+	if date == today {
+		shopRotationCached = rotation
+		shopRotationCachedAt = time.Now()
+	}
+	return rotation
+}
+
+// ShopEnd is when the shop running at t is replaced, the next UTC midnight
+// unless a scheduled shop runs longer.
+func ShopEnd(t time.Time) time.Time {
+	end := t.UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	if shop := ShopForDate(t); shop != nil {
+		if until, err := time.Parse(shopDateLayout, shop.Until); err == nil && until.After(end) {
+			return until
+		}
+	}
+	return end
+}
+
+// ScheduleShop stores a shop for its date, replacing whatever was stored or
+// scheduled for that day.
+func ScheduleShop(shop *models.ShopRotation) error {
+	if ShopRotationCollection == nil {
+		return errors.New("shop rotations are not initialized")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := ShopRotationCollection.ReplaceOne(ctx, bson.M{"date": shop.Date}, shop, options.Replace().SetUpsert(true))
+	resetShopRotationCache()
+	return err
+}
+
+// UnscheduleShop removes the shop scheduled for a day that hasn't started
+// yet. It reports whether there was one.
+func UnscheduleShop(date string) (bool, error) {
+	if ShopRotationCollection == nil {
+		return false, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := ShopRotationCollection.DeleteOne(ctx, bson.M{
+		"date":   bson.M{"$eq": date, "$gt": time.Now().UTC().Format(shopDateLayout)},
+		"source": models.ShopSourceScheduled,
+	})
+	if err != nil {
+		return false, err
+	}
+	resetShopRotationCache()
+	return result.DeletedCount > 0, nil
+}
+
+func resetShopRotationCache() {
+	shopRotationMu.Lock()
+	shopRotationCached = nil
+	shopRotationMu.Unlock()
+}
+
+// findShopRotation returns the latest shop starting on or before date that
+// still runs on it. Rotations stored before shops had an end only cover
+// their own day.
+func findShopRotation(date string) (*models.ShopRotation, error) {
+	if ShopRotationCollection == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"date": bson.M{"$lte": date},
+		"$or": []bson.M{
+			{"until": bson.M{"$gt": date}},
+			{"date": date},
+		},
+	}
+	var rotation models.ShopRotation
+	err := ShopRotationCollection.FindOne(ctx, filter,
+		options.FindOne().SetSort(bson.D{{Key: "date", Value: -1}})).Decode(&rotation)
+	if err != nil {
+		return nil, err
+	}
+	return &rotation, nil
+}
+
+func storeShopRotation(rotation *models.ShopRotation) error {
+	if ShopRotationCollection == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := ShopRotationCollection.InsertOne(ctx, rotation)
+	if err != nil && !mongo.IsDuplicateKeyError(err) {
+		Error.Logf("Failed to store shop rotation for %s: %v", rotation.Date, err)
+	}
+	return err
+}
+
+// FindShopRotations returns the stored rotations from since up to and
+// including until, oldest first. Dates are YYYY-MM-DD.
+func FindShopRotations(since, until string) ([]models.ShopRotation, error) {
+	rotations := []models.ShopRotation{}
+	if ShopRotationCollection == nil {
+		return rotations, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	cursor, err := ShopRotationCollection.Find(ctx,
+		bson.M{"date": bson.M{"$gte": since, "$lte": until}},
+		options.Find().SetSort(bson.D{{Key: "date", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &rotations); err != nil {
+		return nil, err
+	}
+	return rotations, nil
+}
+
+// recentShopTemplates lists what was sold in the days before date, which
+// the no-repeat window keeps out of date's shop.
+func recentShopTemplates(date string, days int) map[string]bool {
+	recent := map[string]bool{}
+	if days <= 0 {
+		return recent
+	}
+	day, err := time.Parse(shopDateLayout, date)
+	if err != nil {
+		return recent
+	}
+	since := day.AddDate(0, 0, -days).Format(shopDateLayout)
+	until := day.AddDate(0, 0, -1).Format(shopDateLayout)
+	rotations, err := FindShopRotations(since, until)
+	if err != nil {
+		Error.Logf("Failed to load recent shop rotations: %v", err)
+		return recent
+	}
+	for _, rotation := range rotations {
+		for _, item := range rotation.Daily {
+			recent[strings.ToLower(item.TemplateID)] = true
+		}
+		for _, item := range rotation.Featured {
+			recent[strings.ToLower(item.TemplateID)] = true
+		}
+	}
+	return recent
+}
+
+// shopRotationPool is every cosmetic in allathena.json except defaults and
+// battle pass rewards.
+func shopRotationPool() []string {
+	shopPoolOnce.Do(func() {
+		raw, err := os.ReadFile("./static/profiles/allathena.json")
+		if err != nil {
+			Error.Logf("Failed to read shop pool: %v", err)
+			return
+		}
+		var allAthena struct {
+			Items map[string]struct {
+				TemplateID string `json:"templateId"`
+			} `json:"items"`
+		}
+		if err := json.Unmarshal(raw, &allAthena); err != nil {
+			Error.Logf("Failed to parse shop pool: %v", err)
+			return
+		}
+
+		battlePass := map[string]bool{}
+		season := os.Getenv("SEASON")
+		if rewardBytes, err := os.ReadFile("./static/responses/BattlePass/Season" + season + ".json"); err == nil {
+			var rewards struct {
+				FreeRewards []map[string]int `json:"freeRewards"`
+				PaidRewards []map[string]int `json:"paidRewards"`
+			}
+			if json.Unmarshal(rewardBytes, &rewards) == nil {
+				for _, tier := range append(rewards.FreeRewards, rewards.PaidRewards...) {
+					for templateID := range tier {
+						battlePass[strings.ToLower(templateID)] = true
+					}
+				}
+			}
+		}
+
+		seen := map[string]bool{}
+		for _, item := range allAthena.Items {
+			lower := strings.ToLower(item.TemplateID)
+			if item.TemplateID == "" || seen[lower] || battlePass[lower] || strings.Contains(lower, "default") {
+				continue
+			}
+			seen[lower] = true
+			shopPool = append(shopPool, item.TemplateID)
+		}
+		// Map order is random; sort so a day's seed always draws the same shop.
+		sort.Strings(shopPool)
+	})
+	return shopPool
+}
+
+type shopCandidate struct {
+	item   models.ShopRotationItem
+	weight int
+}
+
+// GenerateShopRotation draws date's shop from the pool. The draw is seeded by
+// the date, so instances that race to generate a day agree on it.
+func GenerateShopRotation(config *ShopRotationConfig, date string, exclude map[string]bool) *models.ShopRotation {
+	excluded := map[string]bool{}
+	for _, templateID := range config.Exclude {
+		excluded[strings.ToLower(templateID)] = true
+	}
+
+	var fresh, repeats []shopCandidate
+	for _, templateID := range shopRotationPool() {
+		lower := strings.ToLower(templateID)
+		itemType := TemplateType(templateID)
+		if excluded[lower] || !containsFold(config.PoolTypes, itemType) {
+			continue
+		}
+		rarity, price := config.ItemPrice(templateID)
+		weight := config.RarityWeights[rarity]
+		if price <= 0 || weight <= 0 {
+			continue
+		}
+		candidate := shopCandidate{
+			item:   models.ShopRotationItem{TemplateID: templateID, Rarity: rarity, Price: price},
+			weight: weight,
+		}
+		if exclude[lower] {
+			repeats = append(repeats, candidate)
+		} else {
+			fresh = append(fresh, candidate)
+		}
+	}
+
+	hash := fnv.New64a()
+	hash.Write([]byte(date))
+	rng := rand.New(rand.NewSource(int64(hash.Sum64())))
+
+	rotation := &models.ShopRotation{Date: date, Source: models.ShopSourceRotation, Created: time.Now().UTC()}
+	if day, err := time.Parse(shopDateLayout, date); err == nil {
+		rotation.Until = day.AddDate(0, 0, 1).Format(shopDateLayout)
+	}
+	picked := map[string]bool{}
+	draw := func(slots int, types []string) []models.ShopRotationItem {
+		items := []models.ShopRotationItem{}
+		// Items from the no-repeat window only fill slots the rest of the
+		// pool can't.
+		for _, candidates := range [][]shopCandidate{fresh, repeats} {
+			for len(items) < slots {
+				item, ok := drawShopCandidate(rng, candidates, types, picked)
+				if !ok {
+					break
+				}
+				picked[strings.ToLower(item.TemplateID)] = true
+				items = append(items, item)
+			}
+		}
+		return items
+	}
+	rotation.Featured = draw(config.FeaturedSlots, config.FeaturedTypes)
+	rotation.Daily = draw(config.DailySlots, nil)
+	return rotation
+}
+
+// ItemPrice is templateID's rarity and what it sells for on its own, 0 when
+// it has no price.
+func (config *ShopRotationConfig) ItemPrice(templateID string) (string, int) {
+	itemType := TemplateType(templateID)
+	rarity := config.DefaultRarity[itemType]
+	for key, value := range config.Rarities {
+		if strings.EqualFold(key, templateID) {
+			rarity = value
+			break
+		}
+	}
+	for key, prices := range config.Prices {
+		if strings.EqualFold(key, itemType) {
+			return rarity, prices[rarity]
+		}
+	}
+	return rarity, 0
+}
+
+// drawShopCandidate picks one candidate of types (any type when empty) by
+// rarity weight, skipping those already picked.
+func drawShopCandidate(rng *rand.Rand, candidates []shopCandidate, types []string, picked map[string]bool) (models.ShopRotationItem, bool) {
+	total := 0
+	for _, candidate := range candidates {
+		if shopCandidateAllowed(candidate, types, picked) {
+			total += candidate.weight
+		}
+	}
+	if total == 0 {
+		return models.ShopRotationItem{}, false
+	}
+	pick := rng.Intn(total)
+	for _, candidate := range candidates {
+		if !shopCandidateAllowed(candidate, types, picked) {
+			continue
+		}
+		if pick < candidate.weight {
+			return candidate.item, true
+		}
+		pick -= candidate.weight
+	}
+	return models.ShopRotationItem{}, false
+}
+
+func shopCandidateAllowed(candidate shopCandidate, types []string, picked map[string]bool) bool {
+	if picked[strings.ToLower(candidate.item.TemplateID)] {
+		return false
+	}
+	return len(types) == 0 || containsFold(types, TemplateType(candidate.item.TemplateID))
+}
+
+// ShopRotationConfigEntries turns a rotation into catalog_config.json's
+// daily/featured slot format.
+func ShopRotationConfigEntries(rotation *models.ShopRotation) map[string]map[string]interface{} {
+	config := map[string]map[string]interface{}{}
+	for i, item := range rotation.Daily {
+		config["daily"+strconv.Itoa(i+1)] = shopRotationConfigEntry(item)
+	}
+	for i, item := range rotation.Featured {
+		config["featured"+strconv.Itoa(i+1)] = shopRotationConfigEntry(item)
+	}
+	return config
+}
+
+func shopRotationConfigEntry(item models.ShopRotationItem) map[string]interface{} {
+	entry := map[string]interface{}{
+		"itemGrants": []interface{}{item.TemplateID},
+		"price":      item.Price,
+	}
+	if item.CurrencyType != "" {
+		entry["currencyType"] = item.CurrencyType
+		entry["currencySubType"] = item.CurrencySubType
+	}
+	if item.DailyLimit > 0 {
+		entry["dailyLimit"] = item.DailyLimit
+	}
+	if item.WeeklyLimit > 0 {
+		entry["weeklyLimit"] = item.WeeklyLimit
+	}
+	if item.MonthlyLimit > 0 {
+		entry["monthlyLimit"] = item.MonthlyLimit
+	}
+	return entry
+}
+
+func containsFold(slice []string, item string) bool {
+	for _, s := range slice {
+		if strings.EqualFold(s, item) {
+			return true
+		}
+	}
+	return false
+}
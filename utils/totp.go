@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"VentureBackend/static/models"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is how many periods before and after now are still accepted.
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a new base32 encoded 160 bit secret.
+func GenerateTOTPSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(buf), nil
+}
+
+// TOTPProvisioningURI builds the otpauth:// link authenticator apps import.
+func TOTPProvisioningURI(secret, accountName string) string {
+	issuer := "VentureBackend"
+	return fmt.Sprintf("otpauth://totp/%s:%s?secret=%s&issuer=%s&digits=%d&period=%d",
+		url.PathEscape(issuer), url.PathEscape(accountName), secret, url.QueryEscape(issuer), totpDigits, totpPeriod)
+}
+
+func totpCode(key []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// VerifyTOTP checks a code against the secret. Each accepted period can only
+// be used once per key, so a sniffed code can't be replayed.
+func VerifyTOTP(secret, code, replayKey string) bool {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return false
+	}
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return false
+	}
+
+	now := time.Now().Unix() / totpPeriod
+	for i := -totpSkew; i <= totpSkew; i++ {
+		counter := now + int64(i)
+		if !hmac.Equal([]byte(totpCode(key, counter)), []byte(code)) {
+			continue
+		}
+		usedKey := "totpUsed:" + replayKey + ":" + strconv.FormatInt(counter, 10)
+		if _, used := Store.Get(usedKey); used {
+			return false
+		}
+		Store.SetTTL(usedKey, true, time.Duration(totpPeriod*(2*totpSkew+1))*time.Second)
+		return true
+	}
+	return false
+}
+
+// GenerateRecoveryCodes returns n one-time codes in plain text together with
+// their bcrypt hashes for storage.
+func GenerateRecoveryCodes(n int) ([]string, []string, error) {
+	codes := make([]string, 0, n)
+	hashes := make([]string, 0, n)
+	for len(codes) < n {
+		buf := make([]byte, 5)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(totpEncoding.EncodeToString(buf))
+		hash, err := HashSecret(code)
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hash)
+	}
+	return codes, hashes, nil
+}
+
+// MatchRecoveryCode returns the index of the hash the code matches, or -1.
+func MatchRecoveryCode(hashes []string, code string) int {
+	code = strings.ToLower(strings.TrimSpace(code))
+	for i, hash := range hashes {
+		if VerifyPassword(code, hash) {
+			return i
+		}
+	}
+	return -1
+}
+
+// VerifyUserTwoFactor accepts either a current TOTP code or one of the
+// account's recovery codes. A recovery code is removed once it is used.
+func VerifyUserTwoFactor(user *models.User, code string) bool {
+	if !user.TwoFactor.Enabled {
+		return false
+	}
+	if VerifyTOTP(user.TwoFactor.Secret, code, user.AccountID) {
+		return true
+	}
+
+	idx := MatchRecoveryCode(user.TwoFactor.RecoveryCodes, code)
+	if idx == -1 {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := UserCollection.UpdateOne(ctx,
+		bson.M{"accountId": user.AccountID},
+		bson.M{"$pull": bson.M{"twoFactor.recoveryCodes": user.TwoFactor.RecoveryCodes[idx]}})
+	if err != nil || result.ModifiedCount == 0 {
+		Error.Log("Failed to consume recovery code:", err)
+		return false
+	}
+	Backend.Logf("Recovery code used for account %s", user.AccountID)
+	return true
+}
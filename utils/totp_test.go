@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func currentTOTP(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	return totpCode(key, time.Now().Unix()/totpPeriod+offset)
+}
+
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, truncated to six digits.
+	key := []byte("12345678901234567890")
+	for _, tt := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	} {
+		if got := totpCode(key, tt.unix/totpPeriod); got != tt.want {
+			t.Errorf("code at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+
+	if !VerifyTOTP(secret, currentTOTP(t, secret, 0), "verify") {
+		t.Fatalf("current code rejected")
+	}
+	if !VerifyTOTP(secret, currentTOTP(t, secret, 1), "verify") {
+		t.Errorf("code from the next period rejected")
+	}
+	if VerifyTOTP(secret, currentTOTP(t, secret, -3), "verify") {
+		t.Errorf("stale code accepted")
+	}
+	if VerifyTOTP(secret, "12345", "verify") || VerifyTOTP("not base32!", "123456", "verify") {
+		t.Errorf("malformed code or secret accepted")
+	}
+}
+
+func TestVerifyTOTPRejectsReplay(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("GenerateTOTPSecret: %v", err)
+	}
+	code := currentTOTP(t, secret, 0)
+
+	if !VerifyTOTP(secret, code, "replay") {
+		t.Fatalf("first use rejected")
+	}
+	if VerifyTOTP(secret, code, "replay") {
+		t.Errorf("replayed code accepted")
+	}
+	if !VerifyTOTP(secret, code, "other-account") {
+		t.Errorf("replay key of another account blocked the code")
+	}
+}
@@ -2,15 +2,11 @@ package utils
 
 import (
 	"VentureBackend/static/models"
-	"context"
 	"encoding/json"
 	"io/ioutil"
 	"os"
 	"strconv"
 	"strings"
-	"time"
-
-	"go.mongodb.org/mongo-driver/bson"
 )
 
 type LevelRequirement struct {
@@ -98,6 +94,9 @@ func initializeAthena(athena map[string]interface{}) map[string]interface{} {
 	return athena
 }
 
+// CheckAndLevelUp applies pending level and battle pass tier ups to the
+// athena and common_core profiles in place. Callers save both through
+// UpdateProfiles.
 func CheckAndLevelUp(profile *models.Profiles) {
 	season, _ := strconv.Atoi(os.Getenv("SEASON"))
 	InitLevelData(season)
@@ -121,13 +120,7 @@ func CheckAndLevelUp(profile *models.Profiles) {
 	}
 	if levelsGained > 0 {
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-	_, _ = ProfileCollection.UpdateOne(ctx, bson.M{"accountId": profile.AccountID},
-		bson.M{"$set": bson.M{
-			"profiles.athena":      athena,
-			"profiles.common_core": profile.Profiles["common_core"],
-		}})
+	profile.Profiles["athena"] = athena
 }
 
 func handleLevelUps(attributes map[string]interface{}, athena map[string]interface{}) int {
@@ -203,25 +196,35 @@ func claimRewards(bookLevel int, attributes map[string]interface{}, athena map[s
 		paidReward = paidRewards[bookLevel-1]
 	}
 	if freeReward != nil {
-		applyRewards(athena, freeReward, profile)
+		applyRewards(athena, freeReward, profile, "GiftBox:gb_battlepass")
 	}
 	if paidReward != nil {
-		applyRewards(athena, paidReward, profile)
+		applyRewards(athena, paidReward, profile, "GiftBox:gb_battlepass")
 	}
 }
 
-func applyRewards(athena map[string]interface{}, rewards map[string]int, profile *models.Profiles) {
+// GrantRewards gives an account rewards keyed by templateId and wraps them in
+// a giftBoxTemplate gift box on common_core. It returns the gift box id, or ""
+// when nothing was granted.
+func GrantRewards(profile *models.Profiles, rewards map[string]int, giftBoxTemplate string) string {
+	athena, _ := profile.Profiles["athena"].(map[string]interface{})
+	athena = initializeAthena(athena)
+	profile.Profiles["athena"] = athena
+	return applyRewards(athena, rewards, profile, giftBoxTemplate)
+}
+
+func applyRewards(athena map[string]interface{}, rewards map[string]int, profile *models.Profiles, giftBoxTemplate string) string {
 	athena = initializeAthena(athena)
 	lootList := []map[string]interface{}{}
 	commonCore, ok1 := profile.Profiles["common_core"].(map[string]interface{})
 	profile0, ok2 := profile.Profiles["profile0"].(map[string]interface{})
 	if !ok1 || !ok2 {
 		Error.Log("applyRewards: missing common_core or profile0")
-		return
+		return ""
 	}
 	if len(rewards) == 0 {
 		Error.Logf("applyRewards: no rewards for account %s", profile.AccountID)
-		return
+		return ""
 	}
 	for item, quantity := range rewards {
 		itemLower := strings.ToLower(item)
@@ -252,7 +255,7 @@ func applyRewards(athena map[string]interface{}, rewards map[string]int, profile
 			attrs["xp"] = float64(toInt(attrs["xp"])) + float64(quantity)
 		default:
 			if strings.Contains(itemLower, "cosmeticvarianttoken:") {
-				return
+				continue
 			}
 			handleItemRewards(item, quantity, athena)
 		}
@@ -262,10 +265,11 @@ func applyRewards(athena map[string]interface{}, rewards map[string]int, profile
 			"quantity": quantity,
 		})
 	}
+	GiftBoxID := ""
 	if len(lootList) > 0 {
-		GiftBoxID := GenerateRandomID()
+		GiftBoxID = GenerateRandomID()
 		GiftBox := map[string]interface{}{
-			"templateId": "GiftBox:gb_battlepass",
+			"templateId": giftBoxTemplate,
 			"attributes": map[string]interface{}{
 				"max_level_bonus": 0,
 				"fromAccountId":   "",
@@ -275,6 +279,7 @@ func applyRewards(athena map[string]interface{}, rewards map[string]int, profile
 		commonCore["items"].(map[string]interface{})[GiftBoxID] = GiftBox
 	}
 	profile.Profiles["common_core"] = commonCore
+	return GiftBoxID
 }
 
 func handleItemRewards(item string, quantity int, athena map[string]interface{}) {
@@ -304,3 +309,9 @@ func handleItemRewards(item string, quantity int, athena map[string]interface{})
 func leveledUp(attributes map[string]interface{}, amount int) {
 	attributes["book_xp"] = float64(toInt(attributes["book_xp"])) + float64(amount)
 }
+
+// MatchXPLimit caps the XP a dedicated server can award for one match, read
+// from MATCH_XP_LIMIT. 0 disables the cap; 150000 by default.
+func MatchXPLimit() int {
+	return envInt("MATCH_XP_LIMIT", 150000)
+}
@@ -54,10 +54,24 @@ func websocketHandler(w http.ResponseWriter, r *http.Request) {
 	if matchmakingID != "" {
 		user, err := utils.FindUserByMatchmakingID(matchmakingID)
 		if err == nil && user != nil {
+			if user.Banned {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
 			accountId = user.AccountID
 		}
 	}
 
+	ban, err := utils.FindActiveBan(accountId, utils.RequestIP(r), r.Header.Get(utils.HWIDHeader))
+	if err != nil {
+		utils.Matchmaker.Logf("Failed to check bans: %v", err)
+	}
+	if ban != nil {
+		utils.Matchmaker.Logf("Rejected banned player %s", accountId)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	ws, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		utils.Matchmaker.Logf("WebSocket upgrade failed: %v", err)
@@ -230,6 +244,38 @@ func assignSessionsIfReady(queueKey string) {
 	broadcastQueuedStatus(queueKey)
 }
 
+// DisconnectAccount drops every queued player of the account and closes
+// their connections.
+func DisconnectAccount(accountId string) int {
+	var conns []*websocket.Conn
+	var affected []string
+
+	queueLock.Lock()
+	for queueKey, players := range queues {
+		kept := players[:0]
+		for _, p := range players {
+			if p.AccountID == accountId {
+				conns = append(conns, p.Conn)
+				continue
+			}
+			kept = append(kept, p)
+		}
+		if len(kept) != len(players) {
+			queues[queueKey] = kept
+			affected = append(affected, queueKey)
+		}
+	}
+	queueLock.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	for _, queueKey := range affected {
+		broadcastQueuedStatus(queueKey)
+	}
+	return len(conns)
+}
+
 func InitMatchmaker() {
 	port := os.Getenv("MATCHMAKER_PORT")
 	if port == "" {
@@ -21,6 +21,8 @@ type Client struct {
 	AccountId    string
 	DisplayName  string
 	Token        string
+	ClientID     string
+	DeviceID     string
 	Jid          string
 	Resource     string
 	LastPresence struct {
@@ -120,8 +122,12 @@ func xmppHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	remoteIP := utils.RequestIP(r)
+	hwid := r.Header.Get(utils.HWIDHeader)
+
 	var joinedMUCs []string
-	var accountId, displayName, token, jid, resource, ID string
+	var accountId, displayName, jid, resource, ID string
+	var session tokens.StoredToken
 	var Authenticated, clientExists bool
 
 	ws.SetCloseHandler(func(code int, text string) error {
@@ -153,7 +159,7 @@ func xmppHandler(w http.ResponseWriter, r *http.Request) {
 		case "open":
 			handleOpen(ws, &ID, &Authenticated)
 		case "auth":
-			handleAuth(ws, parsed.Doc, &accountId, &displayName, &token, &Authenticated)
+			handleAuth(ws, parsed.Doc, remoteIP, hwid, &accountId, &displayName, &session, &Authenticated)
 		case "iq":
 			handleIQ(ws, parsed.Doc, &accountId, &resource, &jid, clientExists)
 		case "message":
@@ -162,13 +168,15 @@ func xmppHandler(w http.ResponseWriter, r *http.Request) {
 			handlePresence(ws, parsed.Doc, accountId, displayName, jid, resource, &joinedMUCs, clientExists)
 		}
 
-		if !clientExists && accountId != "" && displayName != "" && token != "" && jid != "" && ID != "" && resource != "" && Authenticated {
+		if !clientExists && accountId != "" && displayName != "" && session.Token != "" && jid != "" && ID != "" && resource != "" && Authenticated {
 			ClientsMux.Lock()
 			Clients = append(Clients, &Client{
 				Conn:        ws,
 				AccountId:   accountId,
 				DisplayName: displayName,
-				Token:       token,
+				Token:       session.Token,
+				ClientID:    session.ClientID,
+				DeviceID:    session.DeviceID,
 				Jid:         jid,
 				Resource:    resource,
 				LastPresence: struct {
@@ -236,7 +244,7 @@ func handleOpen(ws *websocket.Conn, ID *string, Authenticated *bool) {
 	ws.WriteMessage(websocket.TextMessage, []byte(featuresXML))
 }
 
-func handleAuth(ws *websocket.Conn, parsed *etree.Document, accountId, displayName, token *string, Authenticated *bool) {
+func handleAuth(ws *websocket.Conn, parsed *etree.Document, remoteIP, hwid string, accountId, displayName *string, session *tokens.StoredToken, Authenticated *bool) {
 	if *accountId != "" {
 		return
 	}
@@ -289,9 +297,19 @@ func handleAuth(ws *websocket.Conn, parsed *etree.Document, accountId, displayNa
 		return
 	}
 
+	ban, err := utils.FindActiveBan(user.AccountID, remoteIP, hwid)
+	if err != nil {
+		utils.XMPP.Log("Failed to check bans:", err)
+	}
+	if ban != nil {
+		utils.XMPP.Logf("Rejected banned client %s", user.Username)
+		Error(ws)
+		return
+	}
+
 	*accountId = user.AccountID
 	*displayName = user.Username
-	*token = object.Token
+	*session = *object
 	*Authenticated = true
 
 	utils.XMPP.Logf("A new client with the username %s has authentificated.", *displayName)
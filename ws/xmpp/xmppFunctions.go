@@ -266,6 +266,77 @@ func findClientByAccountID(accountId string) *Client {
 	return nil
 }
 
+// RefreshDisplayName renames a connected client and re-sends its last
+// presence so friends pick up the new name.
+func RefreshDisplayName(accountId, displayName string) {
+	var conn *websocket.Conn
+	var status string
+	var away bool
+
+	ClientsMux.Lock()
+	for _, c := range Clients {
+		if c.AccountId == accountId {
+			c.DisplayName = displayName
+			conn = c.Conn
+			status = c.LastPresence.Status
+			away = c.LastPresence.Away
+			break
+		}
+	}
+	ClientsMux.Unlock()
+
+	if conn != nil {
+		updatePresenceForFriends(conn, status, away, false)
+	}
+}
+
+// DisconnectTokens closes every connection that authenticated with one of the
+// given access tokens. RemoveClient runs once the read loop sees the close.
+func DisconnectTokens(tokens []string) int {
+	revoked := make(map[string]bool, len(tokens))
+	for _, t := range tokens {
+		revoked[t] = true
+	}
+
+	var conns []*websocket.Conn
+	ClientsMux.Lock()
+	for _, c := range Clients {
+		if revoked[c.Token] {
+			conns = append(conns, c.Conn)
+		}
+	}
+	ClientsMux.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	return len(conns)
+}
+
+// DisconnectSessions closes the connections of an account whose session,
+// identified by client and device, matches. Unlike DisconnectTokens it also
+// finds clients whose access token has been refreshed since they connected.
+func DisconnectSessions(accountId string, match func(clientId, deviceId string) bool) int {
+	var conns []*websocket.Conn
+	ClientsMux.Lock()
+	for _, c := range Clients {
+		if c.AccountId == accountId && match(c.ClientID, c.DeviceID) {
+			conns = append(conns, c.Conn)
+		}
+	}
+	ClientsMux.Unlock()
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+	return len(conns)
+}
+
+// DisconnectAccount closes every connection of the account.
+func DisconnectAccount(accountId string) int {
+	return DisconnectSessions(accountId, func(string, string) bool { return true })
+}
+
 func SendXmppMessageToId(body interface{}, toAccountId string) {
 	if len(Clients) == 0 {
 		return